@@ -1,9 +1,10 @@
 package main
 
 import (
-	"bufio"
+	"errors"
+	"flag"
 	"fmt"
-	"math/rand"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -18,33 +19,77 @@ var (
 	rowRangePattern  = regexp.MustCompile(`(\d+).(\d+)`)   // matches roll ranges like '5-12' and captures the numbers as groups
 	markdownListItem = regexp.MustCompile(`^(\d+\. |\* )`) // identifies a line as a markdown list item, ie. '1. ' or '* '
 
-	// Matches markdown links like '[Link Label](path/to/table)' with a group for 'path/to/table' or
-	// Internal links like '[[path/to/table]]' with a group for 'path/to/table'
-	// Group 1: Either '[foo](' or '[['; Group 2: The path to the table; Group 3: Either ')' or '|foo]]' or ']]'
-	linkMatcher = regexp.MustCompile(`(\[.+?\]\(|\[\[)(.+?)(\)|\|.+?\]\]|\]\])`)
-	usageText   = "Usage: gotableroller {TableName}\nTableName: the name of the markdown file containing the table. " +
+	commands  = []string{"stats", "lint"}
+	usageText = "Usage: gotableroller [--seed N] [--explain] [--max-depth N] [stats|lint] {TableName}\nTableName: the name of the file containing the table. " +
 		"This file must exist the same directory or a subdirectory of gotableroller. TableName may/maynot contain" +
 		"the '.md' extension. It may contain path components as while. Examples: 'Weapons', 'weapons', 'weapons.md', " +
-		"'Items/Weapons.md'"
+		"'Items/Weapons.md'. Tables can be written in markdown, CSV, TSV, JSON or YAML\n--seed N: roll with a fixed seed so the same rolls can be repeated\n" +
+		"--explain: show every die rolled and every table rolled on to reach the result\n" +
+		"--max-depth N: stop with an error when links lead more than N tables deep, 32 unless given\n" +
+		"stats: instead of rolling, show the chance of rolling each entry of the table\n" +
+		"lint: instead of rolling, check the table for gaps, overlaps, unreachable rows, duplicates and dead links. " +
+		"Without a TableName every table is checked"
 )
 
+type options struct {
+	command  string // what to do with the tables, either rolling on them or one of commands
+	query    string
+	seed     int64
+	seeded   bool // a seed was given on the command line
+	explain  bool
+	maxDepth int // how many links deep a roll may go, or 0 for the library's default
+}
+
 // TODO
 // terminal coloring doesnt work on windows
 
 func main() {
 	args := os.Args
 
-	query, err := parseArgs(args)
+	opts, err := parseArgs(args)
 	checkError(err, "Bad command argument")
 
-	rollTables := createRollableTables(query)
+	if opts.command == "lint" {
+		problems := lintTables(opts.query, ".")
+		for _, problem := range problems {
+			fmt.Println(src.Colorize(src.Yellow, problem.Table+": ") + problem.Message)
+		}
+		if len(problems) > 0 {
+			os.Exit(1)
+		}
+		fmt.Println(src.Colorize(src.Green, "No problems found"))
+		return
+	}
 
-	rand.Seed(time.Now().UnixNano())
+	library, err := rollabletable.NewLibrary(".")
+	checkError(err, "Error reading directory")
+	if opts.maxDepth > 0 {
+		library.MaxDepth = opts.maxDepth
+	}
+	rollTables := createRollableTables(library, opts.query)
+
+	if opts.command == "stats" {
+		for _, table := range rollTables {
+			fmt.Println(statsOutput(table))
+		}
+		return
+	}
+
+	source := rollabletable.NewSeededSource(time.Now().UnixNano())
+	if opts.seeded {
+		source = rollabletable.NewSeededSource(opts.seed)
+	}
 
 	var results []string
 	for _, table := range rollTables {
-		result := rollOnTable(table)
-		results = append(results, src.Colorize(src.Green, table.Name+": ")+result)
+		for i := 0; i < rollCount(table); i++ {
+			result, err := library.Explain(table, source)
+			checkError(err, "Error rolling on "+table.Name)
+			if opts.explain {
+				results = append(results, explainOutput(result, 0))
+			}
+			results = append(results, src.Colorize(src.Green, table.Name+": ")+result.Result)
+		}
 	}
 
 	for _, result := range results {
@@ -52,82 +97,203 @@ func main() {
 	}
 }
 
-func rollOnTable(rollTable rollabletable.RollableTable) string {
-	result := rollTable.Roll()
-	for len(linkMatcher.FindStringSubmatch(result)) != 0 {
-		link := getLinkFromResult(result)
-		subTable := createRollableTables(link.pathToTable)
-		subResult := subTable[0].Roll()
-		result = strings.Replace(result, link.originalLink, subResult, 1)
+// rollCount is how many times a table is rolled, once unless its frontmatter says otherwise
+func rollCount(table rollabletable.RollableTable) int {
+	if rolls := table.Metadata().Rolls; rolls > 0 {
+		return rolls
 	}
-	return result
+	return 1
 }
 
-type TableLink struct {
-	originalLink string
-	pathToTable  string
+// explainOutput renders a roll and the rolls nested in it as an indented tree
+func explainOutput(result rollabletable.RollResult, depth int) string {
+	buffer := strings.Builder{}
+	buffer.WriteString(strings.Repeat("  ", depth) + src.Colorize(src.Green, result.Table+":"))
+	if len(result.Dice) > 0 {
+		buffer.WriteString(fmt.Sprintf(" rolled %s, total %d", diceOutput(result.Dice), result.Total))
+	} else {
+		buffer.WriteString(fmt.Sprintf(" drew %d", result.Total)) // the entries of a unique link are drawn, not rolled
+	}
+	if result.Missed {
+		buffer.WriteString(", no row")
+	} else {
+		buffer.WriteString(fmt.Sprintf(", row %s: %s", src.Colorize(src.Yellow, result.Row.Range()), strings.TrimSpace(result.Row.Entry)))
+	}
+	if len(result.Inline) > 0 {
+		buffer.WriteString(", then rolled " + diceOutput(result.Inline))
+	}
+	buffer.WriteString("\n")
+	for _, nested := range result.Nested {
+		buffer.WriteString(explainOutput(nested, depth+1))
+	}
+	return buffer.String()
 }
 
-func getLinkFromResult(result string) TableLink {
-	query := linkMatcher.FindStringSubmatch(result)
-	return TableLink{
-		originalLink: query[0],
-		pathToTable:  query[2],
+// diceOutput lists dice rolls like '2d6 [3 4] = 7, 1d4 [2] = 2'
+func diceOutput(rolls []rollabletable.DiceRoll) string {
+	var dice []string
+	for _, roll := range rolls {
+		dice = append(dice, fmt.Sprintf("%s %v = %d", roll.Dice, roll.Result, roll.Total))
 	}
+	return strings.Join(dice, ", ")
 }
 
-func createRollableTables(query string) (rollTables []rollabletable.RollableTable) {
-	query = standardizeSearch(query)
-	paths, err := findTable(query, ".")
+// createRollableTables finds every table matching query. A query like 'Dungeon#Traps' rolls the table under
+// the 'Traps' heading and one like 'Encounters#Reaction' rolls only the 'Reaction' column.
+func createRollableTables(library *rollabletable.Library, query string) (rollTables []rollabletable.RollableTable) {
+	query, fragment, _ := strings.Cut(query, "#")
+	paths, err := library.Find(query)
 	checkError(err, "Error finding file")
 
 	for _, path := range paths {
-		table, err := rollableTableFromPath(path)
+		tables, err := library.File(path)
+		var table rollabletable.RollableTable
+		if err == nil {
+			table, err = rollabletable.Select(tables, fragment)
+		}
 		if err != nil {
-			fmt.Println(err)
+			fmt.Println(parseErrorOutput(err))
 			continue
 		}
+		for _, warning := range table.Warnings() {
+			fmt.Println(parseErrorOutput(warning))
+		}
 		rollTables = append(rollTables, table)
 	}
 	return rollTables
 }
 
-func rollableTableFromPath(path string) (rollabletable.RollableTable, error) {
-
-	file, err := os.Open(path)
-	checkError(err, "Error reading file")
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	rollTable, err := rollabletable.ParseRollableTable(*scanner, path)
-	if err != nil {
-		return rollabletable.RollableTable{}, fmt.Errorf("Error parsing table: %s, %v", path, err)
+// parseErrorOutput renders an error reading a table file the way compilers do, with a hint on fixing it if
+// there is one
+func parseErrorOutput(err error) string {
+	var parseError *rollabletable.ParseError
+	if !errors.As(err, &parseError) {
+		return err.Error()
 	}
-	return rollTable, nil
+	output := src.Colorize(src.Yellow, parseError.Position()+": ") + parseError.Reason
+	if parseError.Hint != "" {
+		output += "\n    hint: " + parseError.Hint
+	}
+	return output
 }
 
-func parseArgs(args []string) (query string, err error) {
+func parseArgs(args []string) (opts options, err error) {
 
 	if len(args) < 2 {
-		return "", fmt.Errorf("Please provide a table name")
+		return opts, fmt.Errorf("Please provide a table name")
 	}
 
-	// TODO use flags
 	if contains([]string{"-h", "--h", "-help", "--help", "\\h", "\\help"}, args[1]) {
 		fmt.Println(usageText)
 		os.Exit(0)
 	}
 
 	if contains([]string{"-ls", "--ls", "-list", "--list", "\\ls", "\\list"}, args[1]) {
+		query := ""
 		if len(args) > 2 {
 			query = args[2]
 		}
-		output := printDirectoryOutput(".", 0, query)
+		output := printDirectoryOutput(".", 0, standardizeSearch(query))
 		fmt.Println(output)
 		os.Exit(0)
 	}
 
-	return args[1], nil
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	seed := flags.Int64("seed", 0, "")
+	flags.BoolVar(&opts.explain, "explain", false, "")
+	flags.IntVar(&opts.maxDepth, "max-depth", 0, "")
+	// flags may come before or after the table name
+	var positional []string
+	for remaining := args[1:]; len(remaining) > 0; remaining = flags.Args()[1:] {
+		if err := flags.Parse(remaining); err != nil {
+			return opts, err
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		positional = append(positional, flags.Arg(0))
+	}
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			opts.seeded = true
+		}
+	})
+	opts.seed = *seed
+
+	if len(positional) > 1 && contains(commands, positional[0]) {
+		opts.command, positional = positional[0], positional[1:]
+	} else if len(positional) == 1 && positional[0] == "lint" { // lint checks every table when none is given
+		opts.command = "lint"
+		return opts, nil
+	}
+	if len(positional) == 0 {
+		return opts, fmt.Errorf("Please provide a table name")
+	}
+	opts.query = positional[0]
+	return opts, nil
+}
+
+// lintTables validates the tables matching query, or every table under dir when query is empty
+func lintTables(query string, dir string) (problems []rollabletable.Problem) {
+	library, err := rollabletable.NewLibrary(dir)
+	checkError(err, "Error finding files")
+	paths := library.Paths()
+	if query != "" {
+		paths, err = library.Find(query)
+		checkError(err, "Error finding file")
+	}
+
+	for _, path := range paths {
+		tables, err := library.File(path)
+		if err != nil {
+			problems = append(problems, parseProblem(path, err))
+			continue
+		}
+		for _, table := range tables {
+			for _, warning := range table.Warnings() {
+				problems = append(problems, parseProblem(path, warning))
+			}
+			linkExists := func(link string) bool {
+				return library.LinkExists(link, table.Name)
+			}
+			problems = append(problems, rollabletable.Validate(table, linkExists)...)
+		}
+	}
+	return problems
+}
+
+// parseProblem reports a table file that couldn't be read, or a part of it that was skipped, at the line it is on
+func parseProblem(path string, err error) rollabletable.Problem {
+	var parseError *rollabletable.ParseError
+	if errors.As(err, &parseError) {
+		return rollabletable.Problem{Table: parseError.Position(), Message: parseError.Reason}
+	}
+	return rollabletable.Problem{Table: path, Message: err.Error()}
+}
+
+// statsOutput charts the chance of rolling each entry of the table
+func statsOutput(table rollabletable.RollableTable) string {
+	const barWidth = 40
+	chances := table.Chances()
+	highest := 0.0
+	for _, chance := range chances {
+		if chance.Chance > highest {
+			highest = chance.Chance
+		}
+	}
+
+	buffer := strings.Builder{}
+	buffer.WriteString(src.Colorize(src.Green, table.Name+":") + "\n")
+	if description := table.Metadata().Description; description != "" {
+		buffer.WriteString(description + "\n")
+	}
+	for _, chance := range chances {
+		bar := strings.Repeat("█", int(chance.Chance/highest*barWidth+0.5))
+		buffer.WriteString(fmt.Sprintf("%6s %s %6.2f%% %s\n", chance.Range(), src.Colorize(src.Cyan, fmt.Sprintf("%-*s", barWidth, bar)),
+			chance.Chance*100, strings.TrimSpace(chance.Row.Entry)))
+	}
+	return buffer.String()
 }
 
 func printDirectoryOutput(dir string, depth int, query string) string {
@@ -138,7 +304,7 @@ func printDirectoryOutput(dir string, depth int, query string) string {
 	for _, file := range files {
 		if file.IsDir() && !strings.HasPrefix(file.Name(), ".") {
 			directories = append(directories, file)
-		} else if strings.HasSuffix(file.Name(), ".md") {
+		} else if rollabletable.IsTableFile(file.Name()) {
 			if strings.Contains(strings.ToLower(file.Name()), query) {
 				buffer.WriteString(src.Colorize(src.Yellow, strings.Repeat("-", depth)+file.Name()+"\n"))
 			}
@@ -162,27 +328,6 @@ func standardizeSearch(search string) string {
 	return search
 }
 
-func findTable(search string, dir string) (paths []string, err error) {
-	if search == "" {
-		return []string{}, fmt.Errorf("Please provide a table name. Search: %s, Directory: %s", search, dir)
-	}
-
-	err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
-		checkError(err, "Error while walking directory")
-		if d.IsDir() {
-			return nil
-		}
-		if strings.Contains(strings.ToLower(path), strings.ToLower(search)) {
-			paths = append(paths, path)
-		}
-		return nil
-	})
-	if len(paths) == 0 {
-		return []string{}, fmt.Errorf("Table not found: %s", search)
-	}
-	return paths, err
-}
-
 func checkError(err error, msg string) {
 	if err != nil {
 		fmt.Println(msg)
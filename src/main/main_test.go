@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"fmt"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -24,9 +25,23 @@ func Test_standardizeSearch(t *testing.T) {
 }
 
 func Test_parseArgs(t *testing.T) {
-	query, err := parseArgs([]string{"foo", "TestTable"})
+	opts, err := parseArgs([]string{"foo", "TestTable"})
 	assert.NoError(t, err)
-	assert.Equal(t, "TestTable", query)
+	assert.Equal(t, "TestTable", opts.query)
+	assert.False(t, opts.seeded)
+}
+
+func Test_parseArgs_seed(t *testing.T) {
+	opts, err := parseArgs([]string{"foo", "--seed", "42", "TestTable"})
+	assert.NoError(t, err)
+	assert.Equal(t, options{query: "TestTable", seed: 42, seeded: true}, opts)
+
+	opts, err = parseArgs([]string{"foo", "TestTable", "-seed=0"})
+	assert.NoError(t, err)
+	assert.Equal(t, options{query: "TestTable", seed: 0, seeded: true}, opts)
+
+	_, err = parseArgs([]string{"foo", "TestTable", "--seed", "abc"})
+	assert.Error(t, err)
 }
 
 func Test_parseArgs_noQuery(t *testing.T) {
@@ -34,61 +49,76 @@ func Test_parseArgs_noQuery(t *testing.T) {
 	assert.Error(t, err)
 }
 
+// testLibrary indexes the tables under dir
+func testLibrary(t *testing.T, dir string) *rollabletable.Library {
+	library, err := rollabletable.NewLibrary(dir)
+	assert.NoError(t, err)
+	return library
+}
+
+// assertRolls checks that rolling on table with source gives expected
+func assertRolls(t *testing.T, expected string, table rollabletable.RollableTable, source rollabletable.RandomSource) {
+	result, err := testLibrary(t, ".").Roll(table, source)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+}
+
 func Test_findFiles(t *testing.T) {
-	path, err := findTable("testtable.md", "Test")
+	path, err := testLibrary(t, "Test").Find("testtable.md")
 	assert.NoError(t, err)
 	assert.Equal(t, []string{filepath.FromSlash("Test/TestTable.md"), filepath.FromSlash("Test/testdir/SubTestTable.md")}, path)
 }
 
 func Test_findFiles_subDir(t *testing.T) {
-	path, err := findTable("subtesttable.md", "Test")
+	path, err := testLibrary(t, "Test").Find("subtesttable.md")
 	assert.NoError(t, err)
 	assert.Equal(t, []string{filepath.FromSlash("Test/testdir/SubTestTable.md")}, path)
 }
 
 func Test_findFiles_specifyPath(t *testing.T) {
-	path, err := findTable(filepath.FromSlash("testdir/subtesttable.md"), "Test")
+	path, err := testLibrary(t, "Test").Find(filepath.FromSlash("testdir/subtesttable.md"))
 	assert.NoError(t, err)
 	assert.Equal(t, []string{filepath.FromSlash("Test/testdir/SubTestTable.md")}, path)
 }
 
 func Test_findFiles_BadName(t *testing.T) {
-	path, err := findTable("I_Dont_Exist", "Test")
+	path, err := testLibrary(t, "Test").Find("I_Dont_Exist")
 	assert.Error(t, err)
 	assert.Empty(t, path)
 }
 
 func Test_findFiles_EmptyName(t *testing.T) {
-	path, err := findTable("", "Test")
+	path, err := testLibrary(t, "Test").Find("")
 	assert.Error(t, err)
 	assert.Empty(t, path)
 }
 
-func Test_getLinkFromResult(t *testing.T) {
-	link := getLinkFromResult("foo [text label](path/to/file) bar")
-	assert.Equal(t, "[text label](path/to/file)", link.originalLink)
-	assert.Equal(t, "path/to/file", link.pathToTable)
-
-	internalLink := getLinkFromResult("foo [[path/to/file]] bar")
-	assert.Equal(t, "[[path/to/file]]", internalLink.originalLink)
-	assert.Equal(t, "path/to/file", internalLink.pathToTable)
-}
-
-func Test_rollOnTable(t *testing.T) {
+func Test_libraryRoll(t *testing.T) {
 	table, err := rollabletable.ParseRollableTable(*bufio.NewScanner(strings.NewReader("* foo\n* bar\n* baz\n")), "mdtable")
 	assert.NoError(t, err)
-	result := rollOnTable(table)
+	result, err := testLibrary(t, ".").Roll(table, rollabletable.NewSeededSource(1))
+	assert.NoError(t, err)
 	match, err := regexp.Match("foo|bar|baz", []byte(result))
 	assert.NoError(t, err)
 	assert.True(t, match)
 }
 
-func Test_rollOnTable_with_link(t *testing.T) {
-	tables := createRollableTables("TestTable")
-	result := rollOnTable(tables[0])
+func Test_libraryRoll_with_link(t *testing.T) {
+	library := testLibrary(t, ".")
+	tables := createRollableTables(library, "TestTable")
+	result, err := library.Roll(tables[0], rollabletable.NewSeededSource(1))
+	assert.NoError(t, err)
 	assert.NotEmpty(t, result)
 }
 
+func Test_libraryRoll_scripted(t *testing.T) {
+	library := testLibrary(t, ".")
+	tables := createRollableTables(library, "TestTable")
+	result, err := library.Roll(tables[0], rollabletable.NewScriptedSource(3, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, "Option with Sub Option2", result)
+}
+
 func Test_printDirectoryOutput(t *testing.T) {
 	output := printDirectoryOutput(".", 0, "testtable")
 	assert.True(t, strings.Contains(output, "./Test"))
@@ -98,15 +128,15 @@ func Test_printDirectoryOutput(t *testing.T) {
 	assert.True(t, strings.Contains(output, "--SubTestTable.md"))
 }
 
-func Test_rollableTableFromPath(t *testing.T) {
-	table, err := rollableTableFromPath(filepath.FromSlash("Test/TestTable.md"))
+func Test_libraryFile(t *testing.T) {
+	tables, err := testLibrary(t, ".").File(filepath.FromSlash("Test/TestTable.md"))
 	assert.NoError(t, err)
-	assert.True(t, strings.Contains(table.Name, "TestTable"))
-	assert.NotEmpty(t, table.Roll())
+	assert.True(t, strings.Contains(tables[0].Name, "TestTable"))
+	assert.NotEmpty(t, tables[0].Roll())
 }
 
 func Test_createRollableTables(t *testing.T) {
-	tables := createRollableTables("TestTable")
+	tables := createRollableTables(testLibrary(t, "."), "TestTable")
 	assert.NotEmpty(t, tables)
 	assert.True(t, strings.Contains(tables[0].Name, "TestTable"))
 	assert.NotEmpty(t, tables[0].Roll())
@@ -116,3 +146,156 @@ func Test_contains(t *testing.T) {
 	assert.True(t, contains([]string{"foo", "bar", "baz"}, "foo"))
 	assert.False(t, contains([]string{"foo", "bar", "baz"}, "qux"))
 }
+
+func Test_parseArgs_command(t *testing.T) {
+	opts, err := parseArgs([]string{"foo", "stats", "TestTable"})
+	assert.NoError(t, err)
+	assert.Equal(t, "stats", opts.command)
+	assert.Equal(t, "TestTable", opts.query)
+
+	opts, err = parseArgs([]string{"foo", "stats"})
+	assert.NoError(t, err)
+	assert.Equal(t, "", opts.command)
+	assert.Equal(t, "stats", opts.query)
+}
+
+func Test_statsOutput(t *testing.T) {
+	tables, err := testLibrary(t, ".").File(filepath.FromSlash("Test/TestTableTable.md"))
+	assert.NoError(t, err)
+	table := tables[0]
+	output := statsOutput(table)
+	assert.Contains(t, output, "  2-10 ")
+	assert.Contains(t, output, " 45.00% result2")
+	assert.Contains(t, output, strings.Repeat("█", 40))
+	assert.NotContains(t, output, "result4")
+}
+
+func Test_statsOutput_rollList(t *testing.T) {
+	table, err := rollabletable.ParseRollableTable(*bufio.NewScanner(strings.NewReader("| d6 | Weather |\n|---|---|\n| 1, 3, 5 | rain |\n| 2 or 4 | sun |\n| 6 | snow |\n")), "weather")
+	assert.NoError(t, err)
+	output := statsOutput(table)
+	assert.Contains(t, output, "1, 3, 5 ")
+	assert.Contains(t, output, " 50.00% rain")
+	assert.Equal(t, 1, strings.Count(output, "rain"))
+}
+
+func Test_explainOutput(t *testing.T) {
+	library := testLibrary(t, ".")
+	tables, err := library.File(filepath.FromSlash("Test/TestTable.md"))
+	assert.NoError(t, err)
+	result, err := library.Explain(tables[0], rollabletable.NewScriptedSource(3, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, "Option with Sub Option2", result.Result)
+	assert.Equal(t, "Option with [SubTestTable](testdir/SubTestTable)", result.Row.Entry)
+	assert.Len(t, result.Nested, 1)
+	assert.Equal(t, "Sub Option2", result.Nested[0].Result)
+
+	output := explainOutput(result, 0)
+	assert.Contains(t, output, "rolled 1d4 [4] = 4, total 4")
+	assert.Contains(t, output, "\n  ")
+	assert.Contains(t, output, "Sub Option2\n")
+}
+
+func Test_explainOutput_inlineDice(t *testing.T) {
+	result := rollabletable.RollResult{
+		Table:  "Goblins.md",
+		Dice:   []rollabletable.DiceRoll{{Dice: "1d1", Result: []int{1}, Total: 1}},
+		Total:  1,
+		Row:    rollabletable.Row{Min: 1, Max: 1, Entry: "2d6 goblins"},
+		Result: "7 goblins",
+		Inline: []rollabletable.DiceRoll{{Dice: "2d6", Result: []int{3, 4}, Total: 7}},
+	}
+	assert.Contains(t, explainOutput(result, 0), ": 2d6 goblins, then rolled 2d6 [3 4] = 7\n")
+}
+
+func Test_explainOutput_drawn(t *testing.T) {
+	result := rollabletable.RollResult{Table: "Weapons.md", Total: 2, Row: rollabletable.Row{Min: 2, Max: 2, Entry: "axe"}, Result: "axe"}
+	assert.Contains(t, explainOutput(result, 0), " drew 2, row ")
+}
+
+func Test_parseArgs_explain(t *testing.T) {
+	opts, err := parseArgs([]string{"foo", "TestTable", "--explain"})
+	assert.NoError(t, err)
+	assert.True(t, opts.explain)
+}
+
+func Test_lintTables(t *testing.T) {
+	problems := lintTables("", "Test")
+	var messages []string
+	for _, problem := range problems {
+		messages = append(messages, problem.String())
+	}
+	assert.Equal(t, []string{
+		filepath.FromSlash("Test/TestTableTable.md") + ": rolling 11-12 on 1d20 picks no row",
+	}, messages)
+	assert.Empty(t, lintTables("SubTestTable", "Test"))
+}
+
+func Test_parseArgs_maxDepth(t *testing.T) {
+	opts, err := parseArgs([]string{"foo", "--max-depth", "5", "TestTable"})
+	assert.NoError(t, err)
+	assert.Equal(t, options{query: "TestTable", maxDepth: 5}, opts)
+}
+
+func Test_parseArgs_lint(t *testing.T) {
+	opts, err := parseArgs([]string{"foo", "lint"})
+	assert.NoError(t, err)
+	assert.Equal(t, options{command: "lint"}, opts)
+
+	opts, err = parseArgs([]string{"foo", "lint", "Weapons"})
+	assert.NoError(t, err)
+	assert.Equal(t, options{command: "lint", query: "Weapons"}, opts)
+}
+
+func Test_createRollableTables_column(t *testing.T) {
+	tables := createRollableTables(testLibrary(t, "."), "Encounters#Reaction")
+	assert.Len(t, tables, 1)
+	assert.Equal(t, filepath.FromSlash("Test/Encounters.md")+"#Reaction", tables[0].Name)
+	assertRolls(t, "Hungry", tables[0], rollabletable.NewScriptedSource(2))
+
+	tables = createRollableTables(testLibrary(t, "."), "Encounters")
+	assertRolls(t, "Encounter: Sub Option2 travellers, Reaction: Friendly", tables[0], rollabletable.NewScriptedSource(3, 1))
+}
+
+func Test_createRollableTables_heading(t *testing.T) {
+	tables := createRollableTables(testLibrary(t, "."), "Dungeon#Traps")
+	assert.Len(t, tables, 1)
+	assert.Equal(t, filepath.FromSlash("Test/Dungeon.md")+"#Traps", tables[0].Name)
+	assertRolls(t, "Falling block", tables[0], rollabletable.NewScriptedSource(1))
+
+	tables = createRollableTables(testLibrary(t, "."), "Dungeon")
+	assertRolls(t, "Guard room with Pit", tables[0], rollabletable.NewScriptedSource(1, 0))
+}
+
+func Test_frontmatter(t *testing.T) {
+	tables, err := testLibrary(t, ".").File(filepath.FromSlash("Test/Road.md"))
+	assert.NoError(t, err)
+	table := tables[0]
+	assert.Equal(t, 3, rollCount(table))
+	output := statsOutput(table)
+	assert.Contains(t, output, "Who the party meets on the road\n")
+	assert.Contains(t, output, " 66.67% Bandits")
+
+	tables, err = testLibrary(t, ".").File(filepath.FromSlash("Test/TestTable.md"))
+	assert.NoError(t, err)
+	table = tables[0]
+	assert.Equal(t, 1, rollCount(table))
+}
+
+func Test_createRollableTables_csv(t *testing.T) {
+	tables := createRollableTables(testLibrary(t, "."), "Weather#Wind")
+	assert.Len(t, tables, 1)
+	assertRolls(t, "Gale", tables[0], rollabletable.NewScriptedSource(5))
+	assert.Contains(t, printDirectoryOutput("Test", 0, "weather"), "Weather.csv")
+}
+
+func Test_parseErrorOutput(t *testing.T) {
+	err := &rollabletable.ParseError{File: "Loot.md", Line: 9, Column: 3, Reason: "row skipped", Hint: "write the roll like '3'"}
+	output := parseErrorOutput(err)
+	assert.Contains(t, output, "Loot.md:9:3: ")
+	assert.Contains(t, output, "row skipped\n    hint: write the roll like '3'")
+	assert.Equal(t, "Loot.md: not found", parseErrorOutput(fmt.Errorf("Loot.md: not found")))
+
+	assert.Equal(t, rollabletable.Problem{Table: "Loot.md:9:3", Message: "row skipped"}, parseProblem("Loot.md", err))
+	assert.Equal(t, rollabletable.Problem{Table: "Loot.md", Message: "unreadable"}, parseProblem("Loot.md", fmt.Errorf("unreadable")))
+}
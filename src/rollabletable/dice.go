@@ -1,19 +1,16 @@
 package rollabletable
 
 import (
-	"math/rand"
+	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
 
-var (
-	standardDiePattern = regexp.MustCompile(`(\d+)d(\d+)`)
-	digitsDiePattern   = regexp.MustCompile(`66|88`)
-)
-
 type DiceInterpreter interface {
 	interpret(result DieResult) int
+	distribution(dice Dice) Distribution
 }
 
 type AdditionInterpreter struct{}
@@ -26,65 +23,351 @@ func (ai AdditionInterpreter) interpret(result DieResult) int {
 	return sum
 }
 
+// DigitsInterpreter reads each die as one digit of the result, ie. '3' and '5' on a d66 is 35. A d10 reads
+// its 10 as 0, and all zeros is read as the highest result so '0' and '0' on a d% is 100.
 type DigitsInterpreter struct{}
 
 func (di DigitsInterpreter) interpret(result DieResult) int {
-	sum := 0
+	sum, highest := 0, 1
 	for _, v := range result {
 		sum *= 10
-		sum += v
+		sum += v % 10
+		highest *= 10
+	}
+	if sum == 0 && len(result) > 0 {
+		return highest
 	}
 	return sum
 }
 
+// KeepInterpreter sums only the highest or lowest dice of a roll, ie. '4d6kh3' keeps the highest three
+// dice and '4d6dl1' drops the lowest one
+type KeepInterpreter struct {
+	count   int  // number of dice kept, or dropped when drop is set
+	highest bool // keep or drop the highest dice instead of the lowest
+	drop    bool
+}
+
+func (ki KeepInterpreter) interpret(result DieResult) int {
+	sorted := append(DieResult{}, result...)
+	sort.Ints(sorted)
+	if ki.highest != ki.drop { // keeping highest and dropping lowest both keep the end of the sorted roll
+		sorted = sorted[len(sorted)-ki.kept(len(sorted)):]
+	} else {
+		sorted = sorted[:ki.kept(len(sorted))]
+	}
+	return AdditionInterpreter{}.interpret(sorted)
+}
+
+// kept returns the number of dice that count towards the total when rolling dieCount dice
+func (ki KeepInterpreter) kept(dieCount int) int {
+	kept := ki.count
+	if ki.drop {
+		kept = dieCount - ki.count
+	}
+	if kept < 0 {
+		return 0
+	}
+	if kept > dieCount {
+		return dieCount
+	}
+	return kept
+}
+
+// SuccessInterpreter counts the dice of a pool that meet a target number, ie. '6d10>=7'. Dice matching
+// botchOn take away a success and dice matching doubleOn count as two successes.
+type SuccessInterpreter struct {
+	target   comparison
+	botchOn  comparison
+	doubleOn comparison
+}
+
+func (si SuccessInterpreter) interpret(result DieResult) int {
+	successes := 0
+	for _, v := range result {
+		if si.target.matches(v) {
+			successes++
+			if si.doubleOn.matches(v) {
+				successes++
+			}
+		}
+		if si.botchOn.matches(v) {
+			successes--
+		}
+	}
+	return successes
+}
+
+// maxRerolls caps how many times a single die may explode or be rerolled so that a modifier like '1d1!'
+// can't loop forever
+const maxRerolls = 100
+
+type explodeMode int
+
+const (
+	noExplode explodeMode = iota
+	explode               // '3d6!', every die that explodes adds another die to the result
+	compound              // '3d6!!', exploding dice are added onto the die that exploded
+)
+
+type rerollMode int
+
+const (
+	noReroll    rerollMode = iota
+	rerollOnce             // '2d6r1', a die matching the condition is rerolled once
+	rerollUntil            // '4d6rr<3', a die is rerolled until it no longer matches the condition
+)
+
+// comparison is a condition on a single die like the '<3' in '4d6rr<3', the zero comparison matches nothing
+type comparison struct {
+	operator string // one of '=', '<', '<=', '>' or '>='
+	value    int
+}
+
+func (c comparison) String() string {
+	if c.operator == "=" {
+		return strconv.Itoa(c.value)
+	}
+	return c.operator + strconv.Itoa(c.value)
+}
+
+func (c comparison) matches(v int) bool {
+	switch c.operator {
+	case "=":
+		return v == c.value
+	case "<":
+		return v < c.value
+	case "<=":
+		return v <= c.value
+	case ">":
+		return v > c.value
+	case ">=":
+		return v >= c.value
+	}
+	return false
+}
+
 type Dice struct {
 	count int
 	sides int
+	faces []int // when set, each die lands on one of these values instead of 1 to sides
 	DiceInterpreter
+	explode   explodeMode
+	explodeOn comparison // when explode is set, a die matching explodeOn explodes
+	reroll    rerollMode
+	rerollOn  comparison // when reroll is set, a die matching rerollOn is rerolled
 }
 
-func (d Dice) rollAllDice() DieResult {
+func (d Dice) rollAllDice(source RandomSource) DieResult {
 	var result []int
 	for i := 0; i < d.count; i++ {
-		result = append(result, rand.Intn(d.sides)+1)
+		value := d.rollDie(source)
+		switch d.explode {
+		case explode:
+			for n := 0; n < maxRerolls && d.explodeOn.matches(value); n++ {
+				result = append(result, value)
+				value = d.rollDie(source)
+			}
+		case compound:
+			total := value
+			for n := 0; n < maxRerolls && d.explodeOn.matches(value); n++ {
+				value = d.rollDie(source)
+				total += value
+			}
+			value = total
+		}
+		result = append(result, value)
 	}
 	return result
 }
 
+// rollDie rolls a single die, applying any reroll modifier
+func (d Dice) rollDie(source RandomSource) int {
+	value := d.rollFace(source)
+	if d.reroll == noReroll {
+		return value
+	}
+	for n := 0; n < maxRerolls && d.rerollOn.matches(value); n++ {
+		value = d.rollFace(source)
+		if d.reroll == rerollOnce {
+			break
+		}
+	}
+	return value
+}
+
+func (d Dice) rollFace(source RandomSource) int {
+	if len(d.faces) > 0 {
+		return d.faces[source.Intn(len(d.faces))]
+	}
+	return source.Intn(d.sides) + 1
+}
+
+// highestFace is the largest value a single die can land on
+func (d Dice) highestFace() int {
+	if len(d.faces) == 0 {
+		return d.sides
+	}
+	highest := d.faces[0]
+	for _, face := range d.faces {
+		if face > highest {
+			highest = face
+		}
+	}
+	return highest
+}
+
 func (d Dice) Roll() int {
-	return d.DiceInterpreter.interpret(d.rollAllDice())
+	return d.RollWith(defaultSource)
 }
 
-type DieResult []int
+func (d Dice) RollWith(source RandomSource) int {
+	total, _ := d.Explain(source)
+	return total
+}
+
+func (d Dice) Explain(source RandomSource) (int, []DiceRoll) {
+	result := d.rollAllDice(source)
+	total := d.DiceInterpreter.interpret(result)
+	return total, []DiceRoll{{Dice: d.String(), Result: result, Total: total}}
+}
+
+// String writes the dice back out in the notation they are parsed from
+func (d Dice) String() string {
+	notation := strconv.Itoa(d.count) + "d"
+	switch {
+	case reflect.DeepEqual(d.faces, fudgeFaces):
+		notation += "F"
+	case len(d.faces) > 0:
+		var faces []string
+		for _, face := range d.faces {
+			faces = append(faces, strconv.Itoa(face))
+		}
+		notation += "[" + strings.Join(faces, ",") + "]"
+	default:
+		if _, ok := d.DiceInterpreter.(DigitsInterpreter); ok {
+			if d.count == 2 && d.sides == 10 {
+				return "d%"
+			}
+			return "d" + strings.Repeat(strconv.Itoa(d.sides%10), d.count)
+		}
+		notation += strconv.Itoa(d.sides)
+	}
 
-func parseDiceFromString(s string) (Dice, bool) {
-	if digitsDiePattern.MatchString(s) {
-		sides, err := strconv.Atoi(strings.Split(strings.Split(s, "d")[1], "")[0])
-		if err != nil {
-			return Dice{}, false
+	if d.reroll != noReroll {
+		notation += strings.Repeat("r", int(d.reroll)) + d.rerollOn.String()
+	}
+	if d.explode != noExplode {
+		notation += strings.Repeat("!", int(d.explode))
+		if d.explodeOn != (comparison{operator: "=", value: d.highestFace()}) {
+			notation += d.explodeOn.String()
+		}
+	}
+	switch interpreter := d.DiceInterpreter.(type) {
+	case KeepInterpreter:
+		if interpreter.drop {
+			notation += "d"
+		} else {
+			notation += "k"
+		}
+		if interpreter.highest {
+			notation += "h"
+		} else {
+			notation += "l"
+		}
+		notation += strconv.Itoa(interpreter.count)
+	case SuccessInterpreter:
+		target := interpreter.target.String()
+		if interpreter.target.operator == "=" {
+			target = "=" + target
+		}
+		notation += target
+		if interpreter.botchOn.operator != "" {
+			notation += "f" + interpreter.botchOn.String()
+		}
+		if interpreter.doubleOn == (comparison{operator: "=", value: d.highestFace()}) {
+			notation += "ds"
+		} else if interpreter.doubleOn.operator != "" {
+			notation += "ds" + interpreter.doubleOn.String()
 		}
-		return Dice{
-			count:           2,
-			sides:           sides,
-			DiceInterpreter: DigitsInterpreter{},
-		}, true
 	}
+	return notation
+}
 
-	matches := standardDiePattern.FindStringSubmatch(s)
-	if len(matches) == 0 {
-		return Dice{}, false
+type DieResult []int
+
+// inlineDiceBraces matches an expression in braces in an entry, ie. '{1d4+1}', and captures what is inside them
+var inlineDiceBraces = regexp.MustCompile(`\{([^{}]*)\}`)
+
+var fudgeFaces = []int{-1, 0, 1}
+
+// parseDiceFromString finds the first dice expression in s, ie. the '2d6+1' in '| 2d6+1 | result |'.
+// Expressions without any dice in them, such as a plain number, are not considered dice.
+func parseDiceFromString(s string) (DiceExpression, bool) {
+	for start := 0; start < len(s); start++ {
+		if start > 0 && isWordCharacter(s[start-1]) {
+			continue
+		}
+		parser := diceParser{input: s, pos: start}
+		expression, ok := parser.expression()
+		if ok && parser.sawDie {
+			return expression, true
+		}
 	}
-	diceCount, err := strconv.Atoi(matches[1])
-	if err != nil {
-		return Dice{}, false
+	return nil, false
+}
+
+// findDiceTerm finds the first die term in s that is a word of its own, ie. the '2d6' of '2d6 goblins', along
+// with where it starts and ends. Numbers and signs around it are left alone, so 'Level 3 - 1d4 rats' only rolls
+// the 1d4.
+func findDiceTerm(s string) (term DiceExpression, start int, end int, ok bool) {
+	for start = 0; start < len(s); start++ {
+		if c := s[start]; c != 'd' && c != 'D' && (c < '0' || c > '9') || start > 0 && isWordCharacter(s[start-1]) {
+			continue
+		}
+		parser := diceParser{input: s, pos: start}
+		term, ok := parser.factor()
+		if ok && parser.sawDie && (parser.pos == len(s) || !isWordCharacter(s[parser.pos])) {
+			return term, start, parser.pos, true
+		}
 	}
-	diceSides, err := strconv.Atoi(matches[2])
-	if err != nil {
-		return Dice{}, false
+	return nil, 0, 0, false
+}
+
+// rollInlineDice rolls the dice in an entry and writes in their totals, so '2d6 goblins' becomes '7 goblins'.
+// Arithmetic is only rolled inside braces, which are dropped, so '{1d4+1} gold coins' becomes '3 gold coins'.
+func rollInlineDice(entry string, source RandomSource) (string, []DiceRoll) {
+	var rolled strings.Builder
+	var rolls []DiceRoll
+	for {
+		expression, start, end, ok := findDiceTerm(entry)
+		for _, braces := range inlineDiceBraces.FindAllStringSubmatchIndex(entry, -1) {
+			if ok && braces[0] > start {
+				break
+			}
+			if braced, isDice := parseDice(entry[braces[2]:braces[3]]); isDice {
+				expression, start, end, ok = braced, braces[0], braces[1], true
+				break
+			}
+		}
+		if !ok {
+			break
+		}
+		total, dice := expression.Explain(source)
+		rolled.WriteString(entry[:start] + strconv.Itoa(total))
+		rolls = append(rolls, dice...)
+		entry = entry[end:]
 	}
-	return Dice{
-		count:           diceCount,
-		sides:           diceSides,
-		DiceInterpreter: AdditionInterpreter{},
-	}, true
+	rolled.WriteString(entry)
+	return rolled.String(), rolls
+}
+
+// parseDice parses s as a single dice expression, ie. the '1d[2,3,3,4,4,5]' given as a table's dice in its
+// frontmatter. Unlike parseDiceFromString nothing else may be around the expression.
+func parseDice(s string) (DiceExpression, bool) {
+	s = strings.TrimSpace(s)
+	parser := diceParser{input: s}
+	expression, ok := parser.expression()
+	parser.skipSpaces()
+	return expression, ok && parser.sawDie && parser.pos == len(s)
 }
@@ -19,7 +19,7 @@ func Test_rollAllDice(t *testing.T) {
 		count: 2,
 		sides: 6,
 	}
-	result := dice.rollAllDice()
+	result := dice.rollAllDice(defaultSource)
 	fmt.Printf("result: %v\n", result)
 	assert.Equal(t, 2, len(result))
 	assert.GreaterOrEqual(t, result[0], 1)
@@ -32,27 +32,21 @@ func Test_diceFromString(t *testing.T) {
 	s := (" 2d6 ")
 	die, ok := parseDiceFromString(s)
 	assert.True(t, ok)
-	assert.Equal(t, 2, die.count)
-	assert.Equal(t, 6, die.sides)
-	assert.IsType(t, AdditionInterpreter{}, die.DiceInterpreter)
+	assert.Equal(t, Dice{count: 2, sides: 6, DiceInterpreter: AdditionInterpreter{}}, die)
 }
 
 func Test_diceFromString_MDTableHeader(t *testing.T) {
 	s := ("| 2d6 | result |")
 	die, ok := parseDiceFromString(s)
 	assert.True(t, ok)
-	assert.Equal(t, 2, die.count)
-	assert.Equal(t, 6, die.sides)
-	assert.IsType(t, AdditionInterpreter{}, die.DiceInterpreter)
+	assert.Equal(t, Dice{count: 2, sides: 6, DiceInterpreter: AdditionInterpreter{}}, die)
 }
 
 func Test_diceFromString_digitDie(t *testing.T) {
 	s := ("| 1d66 | result |")
 	die, ok := parseDiceFromString(s)
 	assert.True(t, ok)
-	assert.Equal(t, 2, die.count)
-	assert.Equal(t, 6, die.sides)
-	assert.IsType(t, DigitsInterpreter{}, die.DiceInterpreter)
+	assert.Equal(t, Dice{count: 2, sides: 6, DiceInterpreter: DigitsInterpreter{}}, die)
 }
 
 func Test_AdditionInterpreter(t *testing.T) {
@@ -66,3 +60,250 @@ func Test_DigitsInterpreter(t *testing.T) {
 	dieResult := DieResult{1, 2, 3}
 	assert.Equal(t, 123, ai.interpret(dieResult))
 }
+
+func Test_KeepInterpreter(t *testing.T) {
+	dieResult := DieResult{3, 1, 6, 4}
+	assert.Equal(t, 13, KeepInterpreter{count: 3, highest: true}.interpret(dieResult))
+	assert.Equal(t, 1, KeepInterpreter{count: 1}.interpret(dieResult))
+	assert.Equal(t, 13, KeepInterpreter{count: 1, drop: true}.interpret(dieResult))
+	assert.Equal(t, 8, KeepInterpreter{count: 1, highest: true, drop: true}.interpret(dieResult))
+	assert.Equal(t, 14, KeepInterpreter{count: 9, highest: true}.interpret(dieResult))
+	assert.Equal(t, 0, KeepInterpreter{count: 9, drop: true}.interpret(dieResult))
+	assert.Equal(t, DieResult{3, 1, 6, 4}, dieResult)
+}
+
+func Test_diceFromString_keep(t *testing.T) {
+	cases := map[string]KeepInterpreter{
+		"4d6kh3":  {count: 3, highest: true},
+		"2d20k1":  {count: 1, highest: true},
+		"2d20kl1": {count: 1},
+		"4d6dl1":  {count: 1, drop: true},
+		"4d6dh1":  {count: 1, highest: true, drop: true},
+	}
+	for s, keep := range cases {
+		die, ok := parseDiceFromString(s)
+		assert.True(t, ok, s)
+		assert.Equal(t, keep, die.(Dice).DiceInterpreter, s)
+	}
+
+	die, ok := parseDiceFromString("4d6d")
+	assert.True(t, ok)
+	assert.Equal(t, Dice{count: 4, sides: 6, DiceInterpreter: AdditionInterpreter{}}, die)
+}
+
+func Test_rollAllDice_explode(t *testing.T) {
+	dice := Dice{count: 2, sides: 1, explode: explode, explodeOn: comparison{"=", 1}}
+	assert.Equal(t, 2*(maxRerolls+1), len(dice.rollAllDice(defaultSource)))
+
+	dice = Dice{count: 3, sides: 6, explode: explode, explodeOn: comparison{"=", 6}}
+	for i := 0; i < 50; i++ {
+		result := dice.rollAllDice(defaultSource)
+		exploded := 0
+		for _, v := range result {
+			if v == 6 {
+				exploded++
+			}
+		}
+		assert.Equal(t, 3+exploded, len(result))
+	}
+}
+
+func Test_rollAllDice_compound(t *testing.T) {
+	dice := Dice{count: 2, sides: 1, explode: compound, explodeOn: comparison{"=", 1}}
+	assert.Equal(t, DieResult{maxRerolls + 1, maxRerolls + 1}, dice.rollAllDice(defaultSource))
+}
+
+func Test_rollAllDice_reroll(t *testing.T) {
+	dice := Dice{count: 20, sides: 2, reroll: rerollUntil, rerollOn: comparison{"<", 2}}
+	for _, v := range dice.rollAllDice(defaultSource) {
+		assert.Equal(t, 2, v)
+	}
+
+	dice = Dice{count: 20, sides: 1, reroll: rerollOnce, rerollOn: comparison{"=", 1}}
+	assert.Equal(t, 20, len(dice.rollAllDice(defaultSource)))
+}
+
+func Test_comparison(t *testing.T) {
+	assert.True(t, comparison{"=", 3}.matches(3))
+	assert.False(t, comparison{"=", 3}.matches(4))
+	assert.True(t, comparison{"<", 3}.matches(2))
+	assert.False(t, comparison{"<", 3}.matches(3))
+	assert.True(t, comparison{"<=", 3}.matches(3))
+	assert.True(t, comparison{">", 3}.matches(4))
+	assert.False(t, comparison{">", 3}.matches(3))
+	assert.True(t, comparison{">=", 3}.matches(3))
+}
+
+func Test_diceFromString_explodeAndReroll(t *testing.T) {
+	cases := map[string]Dice{
+		"3d6!":     {count: 3, sides: 6, DiceInterpreter: AdditionInterpreter{}, explode: explode, explodeOn: comparison{"=", 6}},
+		"3d6!!":    {count: 3, sides: 6, DiceInterpreter: AdditionInterpreter{}, explode: compound, explodeOn: comparison{"=", 6}},
+		"3d6!>=5":  {count: 3, sides: 6, DiceInterpreter: AdditionInterpreter{}, explode: explode, explodeOn: comparison{">=", 5}},
+		"2d6r1":    {count: 2, sides: 6, DiceInterpreter: AdditionInterpreter{}, reroll: rerollOnce, rerollOn: comparison{"=", 1}},
+		"4d6rr<3":  {count: 4, sides: 6, DiceInterpreter: AdditionInterpreter{}, reroll: rerollUntil, rerollOn: comparison{"<", 3}},
+		"4d6r1kh3": {count: 4, sides: 6, DiceInterpreter: KeepInterpreter{count: 3, highest: true}, reroll: rerollOnce, rerollOn: comparison{"=", 1}},
+	}
+	for s, expected := range cases {
+		die, ok := parseDiceFromString(s)
+		assert.True(t, ok, s)
+		assert.Equal(t, expected, die, s)
+	}
+
+	die, ok := parseDiceFromString("2d6 rolls")
+	assert.True(t, ok)
+	assert.Equal(t, Dice{count: 2, sides: 6, DiceInterpreter: AdditionInterpreter{}}, die)
+}
+
+func Test_SuccessInterpreter(t *testing.T) {
+	dieResult := DieResult{1, 3, 7, 8, 10, 10}
+	assert.Equal(t, 4, SuccessInterpreter{target: comparison{">=", 7}}.interpret(dieResult))
+	assert.Equal(t, 3, SuccessInterpreter{target: comparison{">=", 7}, botchOn: comparison{"=", 1}}.interpret(dieResult))
+	assert.Equal(t, 6, SuccessInterpreter{target: comparison{">=", 7}, doubleOn: comparison{"=", 10}}.interpret(dieResult))
+	assert.Equal(t, -1, SuccessInterpreter{target: comparison{">", 10}, botchOn: comparison{"=", 1}}.interpret(dieResult))
+}
+
+func Test_diceFromString_success(t *testing.T) {
+	cases := map[string]SuccessInterpreter{
+		"6d10>=7":        {target: comparison{">=", 7}},
+		"6d10>7f1":       {target: comparison{">", 7}, botchOn: comparison{"=", 1}},
+		"6d10>=8ds":      {target: comparison{">=", 8}, doubleOn: comparison{"=", 10}},
+		"6d10>=8ds>=9f1": {target: comparison{">=", 8}, doubleOn: comparison{">=", 9}, botchOn: comparison{"=", 1}},
+		"| 5d6>=5 | x |": {target: comparison{">=", 5}},
+	}
+	for s, success := range cases {
+		die, ok := parseDiceFromString(s)
+		assert.True(t, ok, s)
+		assert.Equal(t, success, die.(Dice).DiceInterpreter, s)
+	}
+
+	die, ok := parseDiceFromString("6d10!=10>=8")
+	assert.True(t, ok)
+	assert.Equal(t, comparison{"=", 10}, die.(Dice).explodeOn)
+	assert.Equal(t, SuccessInterpreter{target: comparison{">=", 8}}, die.(Dice).DiceInterpreter)
+
+	die, ok = parseDiceFromString("4d6>=5 foes")
+	assert.True(t, ok)
+	assert.Equal(t, SuccessInterpreter{target: comparison{">=", 5}}, die.(Dice).DiceInterpreter)
+}
+
+func Test_diceFromString_keepWithSuccess(t *testing.T) {
+	for _, s := range []string{"4d6kh3>=5", "4d6dl1>4", "3d6kl2=6"} {
+		_, ok := parseDiceFromString(s)
+		assert.False(t, ok, s)
+		_, ok = parseDice(s)
+		assert.False(t, ok, s)
+	}
+}
+
+func Test_DigitsInterpreter_zeroes(t *testing.T) {
+	var di DigitsInterpreter
+	assert.Equal(t, 5, di.interpret(DieResult{10, 5}))
+	assert.Equal(t, 100, di.interpret(DieResult{10, 10}))
+	assert.Equal(t, 1000, di.interpret(DieResult{10, 10, 10}))
+	assert.Equal(t, 60, di.interpret(DieResult{6, 10}))
+}
+
+func Test_diceFromString_digitDice(t *testing.T) {
+	cases := map[string]DiceExpression{
+		"d666":  Dice{count: 3, sides: 6, DiceInterpreter: DigitsInterpreter{}},
+		"d44":   Dice{count: 2, sides: 4, DiceInterpreter: DigitsInterpreter{}},
+		"d88":   Dice{count: 2, sides: 8, DiceInterpreter: DigitsInterpreter{}},
+		"d%":    Dice{count: 2, sides: 10, DiceInterpreter: DigitsInterpreter{}},
+		"1d00":  Dice{count: 2, sides: 10, DiceInterpreter: DigitsInterpreter{}},
+		"d6:10": digitDice{{count: 1, sides: 6}, {count: 1, sides: 10}},
+		"d100":  Dice{count: 1, sides: 100, DiceInterpreter: AdditionInterpreter{}},
+		"d11":   Dice{count: 1, sides: 11, DiceInterpreter: AdditionInterpreter{}},
+		"d6: x": Dice{count: 1, sides: 6, DiceInterpreter: AdditionInterpreter{}},
+	}
+	for s, expected := range cases {
+		die, ok := parseDiceFromString(s)
+		assert.True(t, ok, s)
+		assert.Equal(t, expected, die, s)
+	}
+}
+
+func Test_diceFromString_digitDiceOverTenSides(t *testing.T) {
+	// a die with more than ten sides can't be read as one digit, so only the d6 is dice
+	for _, s := range []string{"d6:12", "d12:6", "d6:10:11"} {
+		_, ok := parseDice(s)
+		assert.False(t, ok, s)
+		die, ok := parseDiceFromString(s)
+		assert.True(t, ok, s)
+		assert.IsType(t, Dice{}, die, s)
+	}
+}
+
+func Test_digitDice_Roll(t *testing.T) {
+	percentile, _ := parseDiceFromString("d%")
+	mixed, _ := parseDiceFromString("d6:10")
+	for i := 0; i < 100; i++ {
+		result := percentile.Roll()
+		assert.GreaterOrEqual(t, result, 1)
+		assert.LessOrEqual(t, result, 100)
+
+		result = mixed.Roll()
+		assert.GreaterOrEqual(t, result, 10)
+		assert.LessOrEqual(t, result, 69)
+	}
+
+	double, ok := parseDiceFromString("2d22")
+	assert.True(t, ok)
+	for i := 0; i < 50; i++ {
+		assert.Contains(t, []int{22, 23, 24, 32, 33, 34, 42, 43, 44}, double.Roll())
+	}
+}
+
+func Test_diceFromString_faces(t *testing.T) {
+	cases := map[string]Dice{
+		"4dF":             {count: 4, sides: 3, faces: []int{-1, 0, 1}, DiceInterpreter: AdditionInterpreter{}},
+		"d[2,3,3,4,4,5]":  {count: 1, sides: 6, faces: []int{2, 3, 3, 4, 4, 5}, DiceInterpreter: AdditionInterpreter{}},
+		"2d[ -1, 0, 5 ]!": {count: 2, sides: 3, faces: []int{-1, 0, 5}, DiceInterpreter: AdditionInterpreter{}, explode: explode, explodeOn: comparison{"=", 5}},
+	}
+	for s, expected := range cases {
+		die, ok := parseDiceFromString(s)
+		assert.True(t, ok, s)
+		assert.Equal(t, expected, die, s)
+	}
+
+	for _, s := range []string{"d[]", "d[1,2", "d[a]", "df"} {
+		_, ok := parseDiceFromString(s)
+		assert.False(t, ok, s)
+	}
+}
+
+func TestDice_Roll_faces(t *testing.T) {
+	fudge := Dice{count: 4, sides: 3, faces: fudgeFaces, DiceInterpreter: AdditionInterpreter{}}
+	average := Dice{count: 1, sides: 6, faces: []int{2, 3, 3, 4, 4, 5}, DiceInterpreter: AdditionInterpreter{}}
+	for i := 0; i < 100; i++ {
+		assert.GreaterOrEqual(t, fudge.Roll(), -4)
+		assert.LessOrEqual(t, fudge.Roll(), 4)
+		assert.Contains(t, []int{2, 3, 4, 5}, average.Roll())
+	}
+}
+
+func Test_rollInlineDice(t *testing.T) {
+	rolled, dice := rollInlineDice("2d6 goblins", NewScriptedSource(2, 3))
+	assert.Equal(t, "7 goblins", rolled)
+	assert.Equal(t, []DiceRoll{{Dice: "2d6", Result: DieResult{3, 4}, Total: 7}}, dice)
+
+	rolled, _ = rollInlineDice("{1d4+1} gold coins and { d6 } gems", NewScriptedSource(2, 5))
+	assert.Equal(t, "4 gold coins and 6 gems", rolled)
+
+	// only the die term is rolled, not a separator or number before it
+	for entry, expected := range map[string]string{
+		"Monsters - 1d6 orcs": "Monsters - 3 orcs",
+		"Level 3 - 1d4 rats":  "Level 3 - 3 rats",
+		"page 5-1d4":          "page 5-3",
+		"1d4+1 bats":          "3+1 bats",
+		"{1d4+1} bats":        "4 bats",
+		"{north} 2d4 owls":    "{north} 6 owls",
+		"d6s and 3 dogs":      "d6s and 3 dogs",
+	} {
+		rolled, _ = rollInlineDice(entry, NewScriptedSource(2))
+		assert.Equal(t, expected, rolled, entry)
+	}
+
+	rolled, dice = rollInlineDice("a road 6 miles long, {north}", NewScriptedSource(0))
+	assert.Equal(t, "a road 6 miles long, {north}", rolled)
+	assert.Empty(t, dice)
+}
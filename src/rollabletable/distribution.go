@@ -0,0 +1,314 @@
+package rollabletable
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// negligible is the probability below which chains of exploding dice stop being followed
+const negligible = 1e-12
+
+// Distribution maps every possible result of a roll to its probability
+type Distribution map[int]float64
+
+// Results returns the possible results in ascending order
+func (d Distribution) Results() []int {
+	results := make([]int, 0, len(d))
+	for result := range d {
+		results = append(results, result)
+	}
+	sort.Ints(results)
+	return results
+}
+
+func (d Distribution) Mean() float64 {
+	mean := 0.0
+	for result, p := range d {
+		mean += float64(result) * p
+	}
+	return mean
+}
+
+// combine returns the distribution of op applied to a result from a and a result from b
+func combine(a, b Distribution, op func(x, y int) int) Distribution {
+	combined := Distribution{}
+	for x, px := range a {
+		for y, py := range b {
+			combined[op(x, y)] += px * py
+		}
+	}
+	return combined
+}
+
+func add(x, y int) int {
+	return x + y
+}
+
+// sumOf is the distribution of the total of count independent rolls of d
+func sumOf(d Distribution, count int) Distribution {
+	total := Distribution{0: 1}
+	for i := 0; i < count; i++ {
+		total = combine(total, d, add)
+	}
+	return total
+}
+
+func (c constant) Distribution() Distribution {
+	return Distribution{int(c): 1}
+}
+
+func (n negation) Distribution() Distribution {
+	negated := Distribution{}
+	for result, p := range n.operand.Distribution() {
+		negated[-result] += p
+	}
+	return negated
+}
+
+func (be binaryExpression) Distribution() Distribution {
+	return combine(be.left.Distribution(), be.right.Distribution(), be.apply)
+}
+
+func (dd digitDice) Distribution() Distribution {
+	var faces []Distribution
+	for _, dice := range dd {
+		faces = append(faces, dice.faceDistribution())
+	}
+	return digitsDistribution(faces)
+}
+
+func (d Dice) Distribution() Distribution {
+	return d.DiceInterpreter.distribution(d)
+}
+
+// faceDistribution is the chance of a single die landing on each value once rerolls are made
+func (d Dice) faceDistribution() Distribution {
+	faces := Distribution{}
+	if len(d.faces) > 0 {
+		for _, face := range d.faces {
+			faces[face] += 1 / float64(len(d.faces))
+		}
+	} else {
+		for face := 1; face <= d.sides; face++ {
+			faces[face] = 1 / float64(d.sides)
+		}
+	}
+	if d.reroll == noReroll {
+		return faces
+	}
+
+	rerolled := 0.0
+	for face, p := range faces {
+		if d.rerollOn.matches(face) {
+			rerolled += p
+		}
+	}
+	if rerolled == 1 { // every face is rerolled so the last reroll stands
+		return faces
+	}
+	kept := Distribution{}
+	for face, p := range faces {
+		switch {
+		case d.reroll == rerollOnce:
+			kept[face] += rerolled * p
+			if !d.rerollOn.matches(face) {
+				kept[face] += p
+			}
+		case !d.rerollOn.matches(face):
+			kept[face] += p / (1 - rerolled)
+		}
+	}
+	return kept
+}
+
+// dieDistribution is the chance of each score for a single die, following its explosions. score is applied to
+// every die an exploding die adds, or once to the total of a compounding die.
+func (d Dice) dieDistribution(score func(int) int) Distribution {
+	faces := d.faceDistribution()
+	if d.explode == noExplode {
+		scored := Distribution{}
+		for face, p := range faces {
+			scored[score(face)] += p
+		}
+		return scored
+	}
+
+	result := Distribution{}
+	exploding := Distribution{0: 1} // partial totals of dice that are still exploding
+	for n := 0; len(exploding) > 0; n++ {
+		next := Distribution{}
+		for partial, pp := range exploding {
+			for face, pf := range faces {
+				p := pp * pf
+				total := partial + score(face)
+				if d.explode == compound {
+					total = partial + face
+				}
+				if n < maxRerolls && d.explodeOn.matches(face) {
+					if p > negligible {
+						next[total] += p
+					}
+				} else if d.explode == compound {
+					result[score(total)] += p
+				} else {
+					result[total] += p
+				}
+			}
+		}
+		exploding = next
+	}
+	return result
+}
+
+func identity(v int) int {
+	return v
+}
+
+func (ai AdditionInterpreter) distribution(dice Dice) Distribution {
+	return sumOf(dice.dieDistribution(identity), dice.count)
+}
+
+func (si SuccessInterpreter) distribution(dice Dice) Distribution {
+	return sumOf(dice.dieDistribution(func(v int) int {
+		return si.interpret(DieResult{v})
+	}), dice.count)
+}
+
+func (di DigitsInterpreter) distribution(dice Dice) Distribution {
+	var faces []Distribution
+	for i := 0; i < dice.count; i++ {
+		faces = append(faces, dice.faceDistribution())
+	}
+	return digitsDistribution(faces)
+}
+
+// digitsDistribution is the distribution of reading one die from each of faces as a digit
+func digitsDistribution(faces []Distribution) Distribution {
+	number := Distribution{0: 1}
+	for _, face := range faces {
+		number = combine(number, face, func(x, y int) int {
+			return x*10 + y%10
+		})
+	}
+	if p, ok := number[0]; ok && len(faces) > 0 {
+		delete(number, 0)
+		number[DigitsInterpreter{}.interpret(make(DieResult, len(faces)))] += p
+	}
+	return number
+}
+
+// distribution for kept dice follows the dice named by the notation die by die, along with the total of the
+// other dice when those are the ones counted. Every die an exploding die adds is followed as a die of its own,
+// as it is when rolling.
+func (ki KeepInterpreter) distribution(dice Dice) Distribution {
+	faces := dice.faceDistribution()
+	if dice.explode != explode {
+		faces = dice.dieDistribution(identity)
+	}
+
+	type state struct {
+		named []int // the dice named by the notation so far, ie. the highest three of '4d6kh3', sorted
+		rest  int   // the total of the other dice, only followed when they are the ones counted
+		p     float64
+	}
+	add := func(next map[string]state, s state, v int, p float64) {
+		named := append(append([]int{}, s.named...), v)
+		sort.Ints(named)
+		rest := s.rest
+		if len(named) > ki.count {
+			if ki.highest {
+				rest, named = rest+named[0], named[1:]
+			} else {
+				rest, named = rest+named[len(named)-1], named[:len(named)-1]
+			}
+		}
+		if !ki.drop {
+			rest = 0
+		}
+		key := fmt.Sprint(named, rest)
+		n := next[key]
+		n.named, n.rest = named, rest
+		n.p += p
+		next[key] = n
+	}
+
+	states := map[string]state{"": {p: 1}}
+	for i := 0; i < dice.count; i++ {
+		rolled := map[string]state{}
+		rolling := states // dice that still have to be rolled, more than once while they explode
+		for n := 0; len(rolling) > 0; n++ {
+			exploding := map[string]state{}
+			for _, s := range rolling {
+				for v, pf := range faces {
+					p := s.p * pf
+					if dice.explode == explode && n < maxRerolls && dice.explodeOn.matches(v) {
+						if p > negligible {
+							add(exploding, s, v, p)
+						}
+					} else {
+						add(rolled, s, v, p)
+					}
+				}
+			}
+			rolling = exploding
+		}
+		states = rolled
+	}
+
+	result := Distribution{}
+	for _, s := range states {
+		if ki.drop {
+			result[s.rest] += s.p
+		} else {
+			result[AdditionInterpreter{}.interpret(s.named)] += s.p
+		}
+	}
+	return result
+}
+
+// EntryChance is the probability of a roll on a table picking Row. A row written with several rolls, like
+// '1, 3, 5', is split into one Row for each but has a single EntryChance, with every one of them in Rows.
+type EntryChance struct {
+	Row    Row
+	Rows   []Row
+	Chance float64
+}
+
+// Range writes the rolls that pick the entry, ie. '1, 3, 5'
+func (ec EntryChance) Range() string {
+	var ranges []string
+	for _, row := range ec.Rows {
+		ranges = append(ranges, row.Range())
+	}
+	return strings.Join(ranges, ", ")
+}
+
+// Chances returns the probability of picking each row of the table, in the order the rows are written. Rows
+// that can't be rolled are left out.
+func (rt RollableTable) Chances() []EntryChance {
+	byRow := make([]float64, len(rt.rows))
+	for result, p := range rt.dice.Distribution() {
+		for i, row := range rt.rows {
+			if row.contains(result) {
+				byRow[i] += p
+				break
+			}
+		}
+	}
+
+	var chances []EntryChance
+	for i, p := range byRow {
+		if p == 0 {
+			continue
+		}
+		row := rt.rows[i]
+		if last := len(chances) - 1; last >= 0 && row.group != 0 && chances[last].Row.group == row.group {
+			chances[last].Rows = append(chances[last].Rows, row)
+			chances[last].Chance += p
+			continue
+		}
+		chances = append(chances, EntryChance{Row: row, Rows: []Row{row}, Chance: p})
+	}
+	return chances
+}
@@ -0,0 +1,139 @@
+package rollabletable
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func distributionOf(t *testing.T, s string) Distribution {
+	dice, ok := parseDiceFromString(s)
+	assert.True(t, ok, s)
+	return dice.Distribution()
+}
+
+func assertDistribution(t *testing.T, expected Distribution, actual Distribution) {
+	assert.Equal(t, expected.Results(), actual.Results())
+	for result, p := range expected {
+		assert.InDelta(t, p, actual[result], 1e-9, "result %d", result)
+	}
+}
+
+func Test_Distribution_2d6(t *testing.T) {
+	assertDistribution(t, Distribution{
+		2: 1. / 36, 3: 2. / 36, 4: 3. / 36, 5: 4. / 36, 6: 5. / 36, 7: 6. / 36,
+		8: 5. / 36, 9: 4. / 36, 10: 3. / 36, 11: 2. / 36, 12: 1. / 36,
+	}, distributionOf(t, "2d6"))
+	assert.InDelta(t, 7, distributionOf(t, "2d6").Mean(), 1e-9)
+}
+
+func Test_Distribution_arithmetic(t *testing.T) {
+	assertDistribution(t, Distribution{3: .5, 5: .5}, distributionOf(t, "1d2*2+1"))
+	assertDistribution(t, Distribution{-2: .5, -1: .5}, distributionOf(t, "-1d2"))
+	assertDistribution(t, Distribution{0: .25, 1: .5, 2: .25}, distributionOf(t, "1d2/1d2"))
+}
+
+func Test_Distribution_keep(t *testing.T) {
+	assertDistribution(t, Distribution{1: .25, 2: .75}, distributionOf(t, "2d2kh1"))
+	assertDistribution(t, Distribution{1: .75, 2: .25}, distributionOf(t, "2d2kl1"))
+	assert.InDelta(t, 12.2446, distributionOf(t, "4d6dl1").Mean(), 1e-4)
+}
+
+func Test_Distribution_keepExploding(t *testing.T) {
+	// the dice an explosion adds are kept or dropped like any other, so the highest die is never above 6
+	uniform := Distribution{1: 1. / 6, 2: 1. / 6, 3: 1. / 6, 4: 1. / 6, 5: 1. / 6, 6: 1. / 6}
+	assertDistribution(t, uniform, distributionOf(t, "1d6!kh1"))
+
+	// without a 6 the only die is dropped, with one the 6 is kept and the die it adds dropped unless it is higher
+	dropped := distributionOf(t, "1d6!dl1")
+	assert.InDelta(t, 5./6, dropped[0], 1e-9)
+	assert.InDelta(t, 1./6*5./6, dropped[6], 1e-9)
+	assert.Zero(t, dropped[7])
+
+	assertDistribution(t, Distribution{1: .25, 2: .75}, distributionOf(t, "2d2!kh1"))
+	assert.InDelta(t, 1, sumChances(distributionOf(t, "3d4!kh2")), 1e-9)
+}
+
+// sumChances adds up every chance of a distribution
+func sumChances(d Distribution) float64 {
+	total := 0.0
+	for _, p := range d {
+		total += p
+	}
+	return total
+}
+
+func Test_Distribution_reroll(t *testing.T) {
+	assertDistribution(t, Distribution{1: .25, 2: .75}, distributionOf(t, "1d2r1"))
+	assertDistribution(t, Distribution{2: 1}, distributionOf(t, "1d2rr1"))
+}
+
+func Test_Distribution_explode(t *testing.T) {
+	exploding := distributionOf(t, "1d2!")
+	assert.InDelta(t, .5, exploding[1], 1e-9)
+	assert.InDelta(t, .25, exploding[3], 1e-9)
+	assert.InDelta(t, .125, exploding[5], 1e-9)
+	assert.InDelta(t, 3, exploding.Mean(), 1e-6)
+	assert.Zero(t, exploding[2])
+
+	assertDistribution(t, Distribution{maxRerolls + 1: 1}, distributionOf(t, "1d1!!"))
+}
+
+func Test_Distribution_successes(t *testing.T) {
+	assertDistribution(t, Distribution{0: .25, 1: .5, 2: .25}, distributionOf(t, "2d2>=2"))
+	assertDistribution(t, Distribution{-1: .5, 1: .5}, distributionOf(t, "1d2>=2f1"))
+	assertDistribution(t, Distribution{0: .5, 2: .5}, distributionOf(t, "1d2>=2ds"))
+}
+
+func Test_Distribution_digits(t *testing.T) {
+	d66 := distributionOf(t, "d66")
+	assert.Len(t, d66, 36)
+	assert.InDelta(t, 1./36, d66[11], 1e-9)
+	assert.InDelta(t, 1./36, d66[66], 1e-9)
+
+	percentile := distributionOf(t, "d%")
+	assert.Equal(t, 1, percentile.Results()[0])
+	assert.Equal(t, 100, percentile.Results()[99])
+	assert.InDelta(t, .01, percentile[100], 1e-9)
+
+	mixed := distributionOf(t, "d2:10")
+	assert.Equal(t, []int{10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29}, mixed.Results())
+}
+
+func Test_Distribution_fudge(t *testing.T) {
+	fudge := distributionOf(t, "4dF")
+	assert.Equal(t, []int{-4, -3, -2, -1, 0, 1, 2, 3, 4}, fudge.Results())
+	assert.InDelta(t, 19./81, fudge[0], 1e-9)
+}
+
+func TestRollableTable_Chances(t *testing.T) {
+	table, err := fromMDTable(MDTable{{" 2d6 ", " result "}, {"---", "---"}, {" 2-6 ", " low "}, {" 7 ", " seven "}, {" 8-12 ", " high "}}, "chances", nil)
+	assert.NoError(t, err)
+	chances := table.Chances()
+	assert.Len(t, chances, 3)
+	assert.Equal(t, Row{Min: 2, Max: 6, Entry: "low"}, chances[0].Row)
+	assert.InDelta(t, 15./36, chances[0].Chance, 1e-9)
+	assert.InDelta(t, 6./36, chances[1].Chance, 1e-9)
+	assert.InDelta(t, 15./36, chances[2].Chance, 1e-9)
+}
+
+func TestRollableTable_Chances_gaps(t *testing.T) {
+	table, err := fromMDTable(MDTable{{" 1d4 ", " result "}, {"---", "---"}, {" 1 ", " one "}, {" 3-4 ", " high "}}, "gaps", nil)
+	assert.NoError(t, err)
+	chances := table.Chances()
+	one, high := Row{Min: 1, Max: 1, Entry: "one"}, Row{Min: 3, Max: 4, Entry: "high"}
+	assert.Equal(t, []EntryChance{{one, []Row{one}, .25}, {high, []Row{high}, .5}}, chances)
+}
+
+func TestRollableTable_Chances_rollList(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("| d6 | Weather |\n|---|---|\n| 1, 3, 5 | rain |\n| 2 or 4 | sun |\n| 6 | snow |\n")), "weather")
+	assert.NoError(t, err)
+	chances := table.Chances()
+	assert.Len(t, chances, 3)
+	assert.Equal(t, "1, 3, 5", chances[0].Range())
+	assert.InDelta(t, .5, chances[0].Chance, 1e-9)
+	assert.Equal(t, "2, 4", chances[1].Range())
+	assert.Equal(t, "6", chances[2].Range())
+}
@@ -0,0 +1,92 @@
+package rollabletable
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlErrorLine captures the line number the YAML parser starts its errors with, ie. 'line 3: ...'
+var yamlErrorLine = regexp.MustCompile(`^line (\d+): `)
+
+// The reasons for a LinkError besides a link that leads to no table
+var (
+	ErrLinkCycle = errors.New("links back to a table it was rolled from")
+	ErrLinkDepth = errors.New("too many links deep")
+)
+
+// ParseError is something wrong with a file that tables are read from. It stops the file being read when
+// returned as an error, or is collected on a table as one of its Warnings when only part of it was skipped.
+type ParseError struct {
+	File   string
+	Line   int // counted from 1, or 0 when the problem isn't on any one line
+	Column int // counted from 1, or 0 when the problem isn't at any one column
+	Reason string
+	Hint   string // how the problem might be fixed, if there is an obvious way
+}
+
+// Error writes the error the way compilers do, ie. 'Weapons.md:12:3: row skipped, 'one' isn't a roll'
+func (e *ParseError) Error() string {
+	return e.Position() + ": " + e.Reason
+}
+
+// Position writes where the error is as much as it is known, ie. 'Weapons.md:12:3' or 'Weapons.md'
+func (e *ParseError) Position() string {
+	position := e.File
+	if e.Line > 0 {
+		position += ":" + strconv.Itoa(e.Line)
+		if e.Column > 0 {
+			position += ":" + strconv.Itoa(e.Column)
+		}
+	}
+	return position
+}
+
+// Warnings returns the problems found while reading the table that didn't stop it being read, like rows that
+// were skipped
+func (rt RollableTable) Warnings() []*ParseError {
+	return append([]*ParseError{}, rt.warnings...)
+}
+
+func (rt *RollableTable) warn(line, column int, hint string, format string, a ...any) {
+	rt.warnings = append(rt.warnings, &ParseError{File: rt.Name, Line: line, Column: column, Reason: fmt.Sprintf(format, a...), Hint: hint})
+}
+
+// yamlError turns an error from the YAML parser into a ParseError. offset is the line of the file the YAML
+// starts after.
+func yamlError(file string, offset int, reason string, err error) *ParseError {
+	message := err.Error()
+	if typeErr, ok := err.(*yaml.TypeError); ok && len(typeErr.Errors) > 0 {
+		message = typeErr.Errors[0]
+	}
+	message = strings.TrimPrefix(message, "yaml: ")
+
+	parseError := &ParseError{File: file}
+	if line := yamlErrorLine.FindStringSubmatch(message); line != nil {
+		parseError.Line, _ = strconv.Atoi(line[1])
+		parseError.Line += offset
+		message = strings.TrimPrefix(message, line[0])
+	}
+	parseError.Reason = reason + ": " + message
+	return parseError
+}
+
+// LinkError is a link that couldn't be followed while rolling. Chain is the tables rolled on to reach it, starting
+// with the table first rolled on and ending with where the link leads.
+type LinkError struct {
+	Chain []string
+	Err   error
+}
+
+// Error names the whole chain of links, ie. 'Monsters.md -> Animals.md -> Monsters.md: links back to a table...'
+func (e *LinkError) Error() string {
+	return strings.Join(e.Chain, " -> ") + ": " + e.Err.Error()
+}
+
+func (e *LinkError) Unwrap() error {
+	return e.Err
+}
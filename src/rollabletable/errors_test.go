@@ -0,0 +1,75 @@
+package rollabletable
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseError(t *testing.T, err error) *ParseError {
+	var parseError *ParseError
+	assert.True(t, errors.As(err, &parseError), "%v isn't a ParseError", err)
+	return parseError
+}
+
+func TestParseError_Error(t *testing.T) {
+	assert.Equal(t, "Loot.md:12:3: row skipped", (&ParseError{File: "Loot.md", Line: 12, Column: 3, Reason: "row skipped"}).Error())
+	assert.Equal(t, "Loot.md:12: row skipped", (&ParseError{File: "Loot.md", Line: 12, Reason: "row skipped"}).Error())
+	assert.Equal(t, "Loot.md: row skipped", (&ParseError{File: "Loot.md", Column: 3, Reason: "row skipped"}).Error())
+}
+
+func TestParseRollableTables_warnings(t *testing.T) {
+	doc := "---\ntags: loot\n---\n# Loot\n\n| d4 | Loot |\n|:--:|------|\n| 1-2 | copper |\n  |  three | silver |\n| 4 | gold |\n"
+	tables, err := ParseRollableTables(*bufio.NewScanner(strings.NewReader(doc)), "Loot.md")
+	assert.NoError(t, err)
+	assert.Equal(t, []*ParseError{{File: "Loot.md", Line: 9, Column: 6, Reason: "row skipped, 'three' isn't a roll",
+		Hint: "write the roll like '3', '3-7', '19+' or '≤2'"}}, tables[0].Warnings())
+	assert.Equal(t, "Loot.md:9:6: row skipped, 'three' isn't a roll", tables[0].Warnings()[0].Error())
+}
+
+func TestParseRollableTables_brokenSectionWarns(t *testing.T) {
+	doc := "## Good\n* a\n* b\n## Bad\n| d4 | Loot |\n|---|---|\n| one | copper |\n"
+	tables, err := ParseRollableTables(*bufio.NewScanner(strings.NewReader(doc)), "Mixed.md")
+	assert.NoError(t, err)
+	assert.Len(t, tables, 1)
+	var reasons []string
+	for _, warning := range tables[0].Warnings() {
+		reasons = append(reasons, warning.Error())
+	}
+	assert.Equal(t, []string{"Mixed.md:5: table has no rows with a roll"}, reasons)
+}
+
+func TestParseRollableTables_errors(t *testing.T) {
+	for _, test := range []struct {
+		doc      string
+		expected string
+	}{
+		{"Nothing to roll here.", "Bad.md: no list or table found"},
+		{"intro\n| d4 | Loot |\n|---|---|\n| one | copper |\n", "Bad.md:2: table has no rows with a roll"},
+		{"---\ntags: [a\n---\n* a\n", "Bad.md:2: frontmatter not parsable: did not find expected ',' or ']'"},
+		{"---\ntags: [a]\ndice: lots\n---\n* a\n", "Bad.md:3: frontmatter dice 'lots' not parsable"},
+	} {
+		_, err := ParseRollableTables(*bufio.NewScanner(strings.NewReader(test.doc)), "Bad.md")
+		assert.Equal(t, test.expected, parseError(t, err).Error())
+	}
+}
+
+func TestParseTables_errors(t *testing.T) {
+	tables, err := ParseTables("Loot.csv", []byte("d4,Loot\n1,copper\nthree,silver\n4\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Loot.csv:3:1: row skipped, 'three' isn't a roll", "Loot.csv:4:1: row skipped, it has no result"},
+		[]string{tables[0].Warnings()[0].Error(), tables[0].Warnings()[1].Error()})
+
+	tables, err = ParseTables("Loot.yaml", []byte("rows:\n  - [1, copper]\n  - [three, silver]\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Loot.yaml:3:6: row skipped, 'three' isn't a roll", tables[0].Warnings()[0].Error())
+
+	_, err = ParseTables("Loot.yaml", []byte("rows:\n  - [1, copper]\n  - {roll: 2}\n"))
+	assert.Equal(t, "Loot.yaml:3: table not parsable: cannot unmarshal !!map into []string", parseError(t, err).Error())
+
+	_, err = ParseTables("Notes.txt", []byte("Nothing to roll here."))
+	assert.NotEmpty(t, parseError(t, err).Hint)
+}
@@ -0,0 +1,542 @@
+package rollabletable
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DiceExpression is anything that can be rolled for a single number, ie. '2d6', '1d8+1d4' or '(2d6+1)*10'
+type DiceExpression interface {
+	Roll() int
+	RollWith(source RandomSource) int
+	// Explain rolls the expression and also returns every dice term that was rolled on the way
+	Explain(source RandomSource) (int, []DiceRoll)
+	Distribution() Distribution
+	String() string
+}
+
+// DiceRoll records a single dice term of an expression being rolled
+type DiceRoll struct {
+	Dice   string    // the dice rolled, ie. '4d6kh3'
+	Result DieResult // every die rolled, including explosions
+	Total  int       // the dice once interpreted, ie. the sum of the highest three
+}
+
+type constant int
+
+func (c constant) Roll() int {
+	return int(c)
+}
+
+func (c constant) RollWith(source RandomSource) int {
+	return int(c)
+}
+
+func (c constant) Explain(source RandomSource) (int, []DiceRoll) {
+	return int(c), nil
+}
+
+func (c constant) String() string {
+	return strconv.Itoa(int(c))
+}
+
+type negation struct {
+	operand DiceExpression
+}
+
+func (n negation) Roll() int {
+	return n.RollWith(defaultSource)
+}
+
+func (n negation) RollWith(source RandomSource) int {
+	total, _ := n.Explain(source)
+	return total
+}
+
+func (n negation) Explain(source RandomSource) (int, []DiceRoll) {
+	total, rolls := n.operand.Explain(source)
+	return -total, rolls
+}
+
+func (n negation) String() string {
+	if _, ok := n.operand.(binaryExpression); ok {
+		return "-(" + n.operand.String() + ")"
+	}
+	return "-" + n.operand.String()
+}
+
+type binaryExpression struct {
+	operator    byte
+	left, right DiceExpression
+}
+
+func (be binaryExpression) Roll() int {
+	return be.RollWith(defaultSource)
+}
+
+func (be binaryExpression) RollWith(source RandomSource) int {
+	total, _ := be.Explain(source)
+	return total
+}
+
+func (be binaryExpression) Explain(source RandomSource) (int, []DiceRoll) {
+	left, leftRolls := be.left.Explain(source)
+	right, rightRolls := be.right.Explain(source)
+	return be.apply(left, right), append(leftRolls, rightRolls...)
+}
+
+func (be binaryExpression) apply(left, right int) int {
+	switch be.operator {
+	case '+':
+		return left + right
+	case '-':
+		return left - right
+	case '*':
+		return left * right
+	case '/':
+		if right == 0 {
+			return 0
+		}
+		return left / right
+	}
+	return 0
+}
+
+func (be binaryExpression) String() string {
+	left, right := be.left.String(), be.right.String()
+	if inner, ok := be.right.(binaryExpression); ok && precedence(inner.operator) <= precedence(be.operator) {
+		right = "(" + right + ")"
+	}
+	if inner, ok := be.left.(binaryExpression); ok && precedence(inner.operator) < precedence(be.operator) {
+		left = "(" + left + ")"
+	}
+	return left + string(be.operator) + right
+}
+
+func precedence(operator byte) int {
+	if operator == '*' || operator == '/' {
+		return 2
+	}
+	return 1
+}
+
+// diceParser is a recursive descent parser for dice expressions:
+//
+//	expression = term { ("+" | "-") term }
+//	term       = factor { ("*" | "/") factor }
+//	factor     = "-" factor | "(" expression ")" | dice | number
+//	dice       = [number] "d" (digits | sides { keep | explode | reroll } [success])
+//	sides      = number | "F" | "[" number { "," number } "]"
+//	digits     = "%" | number { ":" number }
+//	keep       = ("k" | "kh" | "kl" | "dh" | "dl") number
+//	explode    = ("!" | "!!") [comparison]
+//	reroll     = ("r" | "rr") comparison
+//	success    = ("=" | "<" | "<=" | ">" | ">=") number { "f" comparison | "ds" [comparison] }
+//	comparison = ["=" | "<" | "<=" | ">" | ">="] number
+//
+// It parses the longest valid expression starting at pos and leaves pos just after it.
+type diceParser struct {
+	input  string
+	pos    int
+	sawDie bool
+}
+
+func (p *diceParser) skipSpaces() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *diceParser) peek() byte {
+	if p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+func (p *diceParser) number() (int, bool) {
+	start := p.pos
+	for p.pos < len(p.input) && p.input[p.pos] >= '0' && p.input[p.pos] <= '9' {
+		p.pos++
+	}
+	n, err := strconv.Atoi(p.input[start:p.pos])
+	if err != nil {
+		p.pos = start
+		return 0, false
+	}
+	return n, true
+}
+
+func (p *diceParser) expression() (DiceExpression, bool) {
+	left, ok := p.term()
+	if !ok {
+		return nil, false
+	}
+	for {
+		rewind := p.pos
+		p.skipSpaces()
+		operator := p.peek()
+		if operator != '+' && operator != '-' {
+			p.pos = rewind
+			return left, true
+		}
+		p.pos++
+		right, ok := p.term()
+		if !ok {
+			p.pos = rewind
+			return left, true
+		}
+		left = binaryExpression{operator: operator, left: left, right: right}
+	}
+}
+
+func (p *diceParser) term() (DiceExpression, bool) {
+	left, ok := p.factor()
+	if !ok {
+		return nil, false
+	}
+	for {
+		rewind := p.pos
+		p.skipSpaces()
+		operator := p.peek()
+		if operator != '*' && operator != '/' {
+			p.pos = rewind
+			return left, true
+		}
+		p.pos++
+		right, ok := p.factor()
+		if !ok {
+			p.pos = rewind
+			return left, true
+		}
+		left = binaryExpression{operator: operator, left: left, right: right}
+	}
+}
+
+func (p *diceParser) factor() (DiceExpression, bool) {
+	start := p.pos
+	p.skipSpaces()
+	switch c := p.peek(); {
+	case c == '-':
+		p.pos++
+		operand, ok := p.factor()
+		if !ok {
+			p.pos = start
+			return nil, false
+		}
+		return negation{operand}, true
+	case c == '(':
+		p.pos++
+		inner, ok := p.expression()
+		p.skipSpaces()
+		if !ok || p.peek() != ')' {
+			p.pos = start
+			return nil, false
+		}
+		p.pos++
+		return inner, true
+	case c == 'd' || c == 'D' || (c >= '0' && c <= '9'):
+		count, hasCount := p.number()
+		if c := p.peek(); c != 'd' && c != 'D' {
+			if !hasCount {
+				p.pos = start
+				return nil, false
+			}
+			return constant(count), true
+		}
+		p.pos++
+		die, ok := p.die(count, hasCount)
+		if !ok {
+			p.pos = start
+			return nil, false
+		}
+		p.sawDie = true
+		return die, true
+	}
+	p.pos = start
+	return nil, false
+}
+
+// die parses everything after the 'd' of a die term
+func (p *diceParser) die(count int, hasCount bool) (DiceExpression, bool) {
+	if !hasCount {
+		count = 1
+	}
+	if digits, ok := p.digitDice(); ok {
+		var expression DiceExpression = digits
+		for i := 1; i < count; i++ {
+			expression = binaryExpression{operator: '+', left: expression, right: digits}
+		}
+		return expression, true
+	}
+	dice := Dice{count: count, DiceInterpreter: AdditionInterpreter{}}
+	if faces, ok := p.faces(); ok {
+		dice.sides, dice.faces = len(faces), faces
+	} else if sides, ok := p.number(); ok && sides >= 1 {
+		dice.sides = sides
+	} else {
+		return nil, false
+	}
+	kept := false
+	for {
+		if keep, ok := p.keep(); ok {
+			dice.DiceInterpreter, kept = keep, true
+		} else if !p.explode(&dice) && !p.reroll(&dice) {
+			break
+		}
+	}
+	if success, ok := p.success(dice.highestFace()); ok {
+		if kept { // counting successes would ignore which dice were kept, ie. '4d6kh3>=5'
+			return nil, false
+		}
+		dice.DiceInterpreter = success
+	}
+	return dice, true
+}
+
+// faces parses dice with custom faces, either 'F' for Fudge/FATE dice or a list like '[2,3,3,4,4,5]'
+func (p *diceParser) faces() ([]int, bool) {
+	start := p.pos
+	switch p.peek() {
+	case 'F':
+		p.pos++
+		return fudgeFaces, true
+	case '[':
+		p.pos++
+	default:
+		return nil, false
+	}
+	var faces []int
+	for {
+		p.skipSpaces()
+		negative := p.peek() == '-'
+		if negative {
+			p.pos++
+		}
+		face, ok := p.number()
+		if !ok {
+			p.pos = start
+			return nil, false
+		}
+		if negative {
+			face = -face
+		}
+		faces = append(faces, face)
+		p.skipSpaces()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+			p.pos++
+			return faces, true
+		default:
+			p.pos = start
+			return nil, false
+		}
+	}
+}
+
+// digitDice parses dice read as digits rather than added together. A repeated digit is one die per digit,
+// so 'd66' is two d6 and 'd666' is three. 'd%' and 'd00' are a pair of d10 read from 01 to 00 (100), and
+// mixed dice are listed die by die, so 'd6:10' is a d6 for the tens and a d10 for the ones.
+func (p *diceParser) digitDice() (DiceExpression, bool) {
+	start := p.pos
+	if p.peek() == '%' {
+		p.pos++
+		return Dice{count: 2, sides: 10, DiceInterpreter: DigitsInterpreter{}}, true
+	}
+	sides, ok := p.number()
+	if !ok {
+		return nil, false
+	}
+	if p.peek() == ':' {
+		mixed := digitDice{{count: 1, sides: sides}}
+		for p.peek() == ':' {
+			p.pos++
+			sides, ok := p.number()
+			if !ok || sides < 2 || sides > 10 {
+				p.pos = start
+				return nil, false
+			}
+			mixed = append(mixed, Dice{count: 1, sides: sides})
+		}
+		if mixed[0].sides < 2 || mixed[0].sides > 10 { // each die is read as one digit
+			p.pos = start
+			return nil, false
+		}
+		return mixed, true
+	}
+	text := p.input[start:p.pos]
+	digit := text[0]
+	if len(text) < 2 || strings.Count(text, string(digit)) != len(text) || digit == '1' {
+		p.pos = start
+		return nil, false
+	}
+	if digit == '0' {
+		return Dice{count: len(text), sides: 10, DiceInterpreter: DigitsInterpreter{}}, true
+	}
+	return Dice{count: len(text), sides: int(digit - '0'), DiceInterpreter: DigitsInterpreter{}}, true
+}
+
+// success parses an optional target number for a dice pool, ie. the '>=7f1' in '6d10>=7f1'. 'f' marks the
+// faces that botch and 'ds' the faces that count twice, which are the highest face if no comparison follows.
+func (p *diceParser) success(sides int) (SuccessInterpreter, bool) {
+	var success SuccessInterpreter
+	if c := p.peek(); c != '=' && c != '<' && c != '>' {
+		return success, false
+	}
+	target, ok := p.comparison()
+	if !ok {
+		return success, false
+	}
+	success.target = target
+	for {
+		start := p.pos
+		switch {
+		case strings.HasPrefix(p.input[p.pos:], "f"):
+			p.pos++
+			botchOn, ok := p.comparison()
+			if !ok {
+				p.pos = start
+				return success, true
+			}
+			success.botchOn = botchOn
+		case strings.HasPrefix(p.input[p.pos:], "ds"):
+			p.pos += 2
+			success.doubleOn = comparison{operator: "=", value: sides}
+			if doubleOn, ok := p.comparison(); ok {
+				success.doubleOn = doubleOn
+			}
+		default:
+			return success, true
+		}
+	}
+}
+
+// explode parses an optional exploding modifier, ie. the '!' in '3d6!' or the '!!>4' in '3d6!!>4'
+func (p *diceParser) explode(dice *Dice) bool {
+	if p.peek() != '!' {
+		return false
+	}
+	p.pos++
+	dice.explode = explode
+	if p.peek() == '!' {
+		p.pos++
+		dice.explode = compound
+	}
+	dice.explodeOn = comparison{operator: "=", value: dice.highestFace()}
+	if on, ok := p.comparison(); ok {
+		dice.explodeOn = on
+	}
+	return true
+}
+
+// reroll parses an optional reroll modifier, ie. the 'r1' in '2d6r1' or the 'rr<3' in '4d6rr<3'
+func (p *diceParser) reroll(dice *Dice) bool {
+	start := p.pos
+	if p.peek() != 'r' {
+		return false
+	}
+	p.pos++
+	mode := rerollOnce
+	if p.peek() == 'r' {
+		p.pos++
+		mode = rerollUntil
+	}
+	on, ok := p.comparison()
+	if !ok {
+		p.pos = start
+		return false
+	}
+	dice.reroll = mode
+	dice.rerollOn = on
+	return true
+}
+
+// comparison parses a condition on a single die like '<3' or '6'
+func (p *diceParser) comparison() (comparison, bool) {
+	start := p.pos
+	operator := "="
+	for _, candidate := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(p.input[p.pos:], candidate) {
+			operator = candidate
+			p.pos += len(candidate)
+			break
+		}
+	}
+	value, ok := p.number()
+	if !ok {
+		p.pos = start
+		return comparison{}, false
+	}
+	return comparison{operator: operator, value: value}, true
+}
+
+// digitDice is a set of dice with different sides read as digits, ie. 'd6:10'
+type digitDice []Dice
+
+func (dd digitDice) Roll() int {
+	return dd.RollWith(defaultSource)
+}
+
+func (dd digitDice) RollWith(source RandomSource) int {
+	total, _ := dd.Explain(source)
+	return total
+}
+
+func (dd digitDice) Explain(source RandomSource) (int, []DiceRoll) {
+	var result DieResult
+	for _, dice := range dd {
+		result = append(result, dice.rollAllDice(source)...)
+	}
+	total := DigitsInterpreter{}.interpret(result)
+	return total, []DiceRoll{{Dice: dd.String(), Result: result, Total: total}}
+}
+
+func (dd digitDice) String() string {
+	var sides []string
+	for _, dice := range dd {
+		sides = append(sides, strconv.Itoa(dice.sides))
+	}
+	return "d" + strings.Join(sides, ":")
+}
+
+// keep parses an optional keep/drop modifier, ie. the 'kh3' in '4d6kh3'
+func (p *diceParser) keep() (KeepInterpreter, bool) {
+	start := p.pos
+	var keep KeepInterpreter
+	switch p.peek() {
+	case 'k':
+		keep.highest = true
+	case 'd':
+		keep.drop = true
+	default:
+		return keep, false
+	}
+	p.pos++
+	switch p.peek() {
+	case 'h':
+		keep.highest = true
+		p.pos++
+	case 'l':
+		keep.highest = false
+		p.pos++
+	default:
+		if keep.drop { // a bare 'd' is not a modifier
+			p.pos = start
+			return keep, false
+		}
+	}
+	count, ok := p.number()
+	if !ok {
+		p.pos = start
+		return keep, false
+	}
+	keep.count = count
+	return keep, true
+}
+
+func isWordCharacter(b byte) bool {
+	return b == '_' || unicode.IsLetter(rune(b)) || unicode.IsDigit(rune(b))
+}
@@ -0,0 +1,79 @@
+package rollabletable
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseDiceFromString_arithmetic(t *testing.T) {
+	cases := map[string]int{
+		"1d1+1":         2,
+		"2d1 - 1":       1,
+		"3d1*2":         6,
+		"7d1/2":         3,
+		"(1d1+2)*3":     9,
+		"1d1+1d1+1d1":   3,
+		"-1d1+5":        4,
+		"| 4d1+1 | x |": 5,
+		"d1":            1,
+		"2 + 2*2d1":     6,
+	}
+	for s, expected := range cases {
+		expression, ok := parseDiceFromString(s)
+		assert.True(t, ok, s)
+		assert.Equal(t, expected, expression.Roll(), s)
+	}
+}
+
+func Test_parseDiceFromString_stopsAtText(t *testing.T) {
+	expression, ok := parseDiceFromString("2d1+1 goblins - 3 wolves")
+	assert.True(t, ok)
+	assert.Equal(t, 3, expression.Roll())
+
+	expression, ok = parseDiceFromString("1d1x[[Treasure]]")
+	assert.True(t, ok)
+	assert.Equal(t, 1, expression.Roll())
+}
+
+func Test_parseDiceFromString_noDice(t *testing.T) {
+	for _, s := range []string{"", "| roll | result |", "12", "(1+2)", "odd", "d0", "(1+2"} {
+		_, ok := parseDiceFromString(s)
+		assert.False(t, ok, s)
+	}
+}
+
+func Test_binaryExpression_divideByZero(t *testing.T) {
+	expression := binaryExpression{operator: '/', left: constant(4), right: constant(0)}
+	assert.Equal(t, 0, expression.Roll())
+}
+
+func Test_DiceExpression_String(t *testing.T) {
+	for _, s := range []string{
+		"2d6", "2d6+1", "1d8+1d4-2", "(2d6+1)*10", "2*(1d4+1)", "1d20-(1d4-1)", "-(1d4+1)", "-1d4",
+		"4d6kh3", "2d20kl1", "4d6dl1", "4d6dh1", "3d6!", "3d6!!", "3d6!>=5", "2d6r1", "4d6rr<3",
+		"6d10>=7", "6d10>7f1ds", "6d10=10", "4dF", "1d[2,3,3,4,4,5]", "d66", "d666", "d%", "d6:10",
+	} {
+		expression, ok := parseDiceFromString(s)
+		assert.True(t, ok, s)
+		if s[0] == 'd' && s != "d66" && s != "d666" && s != "d%" && s != "d6:10" {
+			s = "1" + s
+		}
+		assert.Equal(t, s, expression.String())
+	}
+}
+
+func Test_DiceExpression_Explain(t *testing.T) {
+	expression, _ := parseDiceFromString("4d6kh3+1d4+1")
+	total, rolls := expression.Explain(NewScriptedSource(0, 5, 3, 2, 1))
+	assert.Equal(t, 6+4+3+2+1, total)
+	assert.Equal(t, []DiceRoll{
+		{Dice: "4d6kh3", Result: DieResult{1, 6, 4, 3}, Total: 13},
+		{Dice: "1d4", Result: DieResult{2}, Total: 2},
+	}, rolls)
+
+	expression, _ = parseDiceFromString("d6:10")
+	total, rolls = expression.Explain(NewScriptedSource(2, 9))
+	assert.Equal(t, 30, total)
+	assert.Equal(t, []DiceRoll{{Dice: "d6:10", Result: DieResult{3, 10}, Total: 30}}, rolls)
+}
@@ -0,0 +1,236 @@
+package rollabletable
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a kind of file tables can be read from, like markdown or CSV
+type Format struct {
+	Name       string
+	Extensions []string                                                   // ie. '.md'
+	Parse      func(content []byte, name string) ([]RollableTable, error) // reads every table in content, naming them after name
+}
+
+var (
+	formatsMu sync.RWMutex // formats can be registered while a library is reading files
+	formats   = []Format{
+		{Name: "json", Extensions: []string{".json"}, Parse: parseYAMLTable},
+		{Name: "yaml", Extensions: []string{".yaml", ".yml"}, Parse: parseYAMLTable},
+		{Name: "markdown", Extensions: []string{".md", ".markdown"}, Parse: parseMarkdown},
+		{Name: "tsv", Extensions: []string{".tsv", ".tab"}, Parse: parseDelimited('\t')},
+		{Name: "csv", Extensions: []string{".csv"}, Parse: parseDelimited(',')},
+	}
+)
+
+// RegisterFormat adds a format tables can be read from. It takes precedence over the formats registered before
+// it for its extensions. Libraries only find files of the formats registered before they are made.
+func RegisterFormat(format Format) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	formats = append(formats, format)
+}
+
+// IsTableFile reports whether path has the extension of one of the formats tables are read from
+func IsTableFile(path string) bool {
+	_, ok := formatByExtension(path)
+	return ok
+}
+
+func formatByExtension(path string) (Format, bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	extension := strings.ToLower(filepath.Ext(path))
+	for i := len(formats) - 1; i >= 0; i-- {
+		for _, formatExtension := range formats[i].Extensions {
+			if extension == formatExtension {
+				return formats[i], true
+			}
+		}
+	}
+	return Format{}, false
+}
+
+// ParseTables reads every table in a file, in the format picked by the file's extension
+func ParseTables(path string, content []byte) ([]RollableTable, error) {
+	if format, ok := formatByExtension(path); ok {
+		return format.Parse(content, path)
+	}
+	return nil, &ParseError{File: path, Reason: "not in a known table format",
+		Hint: "use a markdown, CSV, TSV, JSON or YAML file with its usual extension"}
+}
+
+func parseMarkdown(content []byte, name string) ([]RollableTable, error) {
+	return ParseRollableTables(*bufio.NewScanner(bytes.NewReader(content)), name)
+}
+
+// yamlTable is how a table is written in YAML or JSON, either as a plain list of entries or as
+//
+//	dice: 2d6
+//	columns: [Reaction]
+//	rows:
+//	  - [2-5, Hostile]
+//	  - [6-8, Wary]
+//	  - [9+, Friendly]
+//
+// alongside any of the metadata frontmatter can have
+type yamlTable struct {
+	Columns []string   `yaml:"columns"` // names of the result columns of a table with more than one
+	Rows    [][]string `yaml:"rows"`    // each row is its roll followed by its result columns
+	Entries []string   `yaml:"entries"` // the entries of a list, used instead of rows
+}
+
+// parseYAMLTable reads a table written in YAML, or in JSON since that is also YAML
+func parseYAMLTable(content []byte, name string) ([]RollableTable, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, yamlError(name, 0, "table not parsable", err)
+	}
+	if len(document.Content) == 0 {
+		return nil, &ParseError{File: name, Reason: "no table found", Hint: "write a list of entries, or give the table 'rows' or 'entries'"}
+	}
+
+	if document.Content[0].Kind == yaml.SequenceNode {
+		var entries []string
+		if err := document.Decode(&entries); err != nil {
+			return nil, yamlError(name, 0, "entries not parsable", err)
+		}
+		if len(entries) == 0 {
+			return nil, &ParseError{File: name, Line: document.Content[0].Line, Reason: "list has no entries"}
+		}
+		return []RollableTable{fromMDList(entries, name, nil)}, nil
+	}
+
+	var written yamlTable
+	if err := document.Decode(&written); err != nil {
+		return nil, yamlError(name, 0, "table not parsable", err)
+	}
+	lines := strings.Split(string(content), "\n")
+	metadata, err := parseFrontmatter(lines, name, 0)
+	if err != nil {
+		return nil, err
+	}
+	var table RollableTable
+	switch {
+	case len(written.Entries) > 0:
+		table = fromMDList(written.Entries, name, metadata.Weights)
+	case len(written.Rows) > 0:
+		table, err = fromRows(append([]string{""}, written.Columns...), written.Rows, yamlRowPositions(document.Content[0]), name)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, &ParseError{File: name, Reason: "no rows or entries found", Hint: "give the table 'rows' or 'entries'"}
+	}
+	table.applyMetadata(metadata, true, frontmatterLine(lines, "dice", 0))
+	return []RollableTable{table}, nil
+}
+
+// yamlRowPositions finds the roll of each of the rows of a table written in YAML
+func yamlRowPositions(mapping *yaml.Node) []position {
+	var positions []position
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != "rows" {
+			continue
+		}
+		for _, row := range mapping.Content[i+1].Content {
+			if len(row.Content) > 0 {
+				row = row.Content[0]
+			}
+			positions = append(positions, position{line: row.Line, column: row.Column})
+		}
+	}
+	return positions
+}
+
+// parseDelimited reads a table from a spreadsheet export. The first record is the header, with the dice in its
+// first cell like a markdown table. A single column is read as a list.
+func parseDelimited(delimiter rune) func(content []byte, name string) ([]RollableTable, error) {
+	return func(content []byte, name string) ([]RollableTable, error) {
+		reader := csv.NewReader(bytes.NewReader(content))
+		reader.Comma = delimiter
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+		var records [][]string
+		var positions []position
+		singleColumn := true
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if csvErr, ok := err.(*csv.ParseError); ok {
+				return nil, &ParseError{File: name, Line: csvErr.Line, Column: csvErr.Column, Reason: csvErr.Err.Error()}
+			}
+			if err != nil {
+				return nil, &ParseError{File: name, Reason: err.Error()}
+			}
+			line, column := reader.FieldPos(0)
+			records = append(records, record)
+			positions = append(positions, position{line: line, column: column})
+			if len(record) > 1 {
+				singleColumn = false
+			}
+		}
+
+		if len(records) < 2 {
+			return nil, &ParseError{File: name, Reason: "no rows found", Hint: "the first line is the header, the rows follow it"}
+		}
+		if singleColumn {
+			var entries MDList
+			for _, record := range records[1:] {
+				if strings.TrimSpace(record[0]) != "" {
+					entries = append(entries, record[0])
+				}
+			}
+			if len(entries) == 0 {
+				return nil, &ParseError{File: name, Reason: "list has no entries"}
+			}
+			return []RollableTable{fromMDList(entries, name, nil)}, nil
+		}
+
+		table, err := fromRows(records[0], records[1:], positions[1:], name)
+		if err != nil {
+			return nil, err
+		}
+		return []RollableTable{table}, nil
+	}
+}
+
+// fromRows makes a table from a header and rows like those of a markdown table. positions locates the roll of
+// each row in the file, if known.
+func fromRows(header []string, rows [][]string, positions []position, name string) (RollableTable, error) {
+	for len(header) < 2 {
+		header = append(header, "")
+	}
+	table := MDTable{header}
+	tablePositions := []position{{}}
+	var skipped []position
+	for i, row := range rows {
+		var at position
+		if i < len(positions) {
+			at = positions[i]
+		}
+		if len(row) < 2 {
+			skipped = append(skipped, at)
+			continue
+		}
+		table = append(table, row)
+		tablePositions = append(tablePositions, at)
+	}
+	rollableTable, err := fromMDTable(table, name, tablePositions)
+	if err != nil {
+		return rollableTable, err
+	}
+	for _, at := range skipped {
+		rollableTable.warn(at.line, at.column, "", "row skipped, it has no result")
+	}
+	return rollableTable, nil
+}
@@ -0,0 +1,89 @@
+package rollabletable
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func rowEntries(table RollableTable) []string {
+	var entries []string
+	for _, row := range table.rows {
+		entries = append(entries, row.Range()+" "+row.Entry)
+	}
+	return entries
+}
+
+func TestParseTables_csv(t *testing.T) {
+	tables, err := ParseTables("Encounters.csv", []byte("d4,Encounter,Reaction\n1-2,Goblins,Hostile\n3,\"Rats, many\",Hungry\n4,Pilgrims,Friendly\n"))
+	assert.NoError(t, err)
+	assert.Len(t, tables, 1)
+	assert.Equal(t, "Encounters.csv", tables[0].Name)
+	assert.Equal(t, []string{"Encounter", "Reaction"}, tables[0].Columns())
+	assert.Equal(t, []string{"1-2 Encounter: Goblins, Reaction: Hostile", "3 Encounter: Rats, many, Reaction: Hungry", "4 Encounter: Pilgrims, Reaction: Friendly"}, rowEntries(tables[0]))
+	assert.Equal(t, "1d4", tables[0].dice.String())
+}
+
+func TestParseTables_csvList(t *testing.T) {
+	tables, err := ParseTables("Names.csv", []byte("Name\nAda\n\nBo\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1 Ada", "2 Bo"}, rowEntries(tables[0]))
+
+	_, err = ParseTables("Empty.csv", []byte("Name\n"))
+	assert.Error(t, err)
+}
+
+func TestParseTables_tsv(t *testing.T) {
+	tables, err := ParseTables("Loot.tsv", []byte("2d6\tLoot\n2-6\tcopper\n7-12\tsilver\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2-6 copper", "7-12 silver"}, rowEntries(tables[0]))
+	assert.Equal(t, "2d6", tables[0].dice.String())
+}
+
+func TestParseTables_json(t *testing.T) {
+	tables, err := ParseTables("Reaction.json", []byte(`{"dice": "2d6", "description": "How they react", "rows": [["2-5", "Hostile"], [7, "Wary"], ["8+", "Friendly"]]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2-5 Hostile", "7 Wary", "8+ Friendly"}, rowEntries(tables[0]))
+	assert.Equal(t, "2d6", tables[0].dice.String())
+	assert.Equal(t, "How they react", tables[0].Metadata().Description)
+
+	tables, err = ParseTables("Names.json", []byte(`["Ada", "Bo"]`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1 Ada", "2 Bo"}, rowEntries(tables[0]))
+
+	_, err = ParseTables("Broken.json", []byte(`{"rows": [`))
+	assert.Error(t, err)
+}
+
+func TestParseTables_yaml(t *testing.T) {
+	tables, err := ParseTables("Road.yaml", []byte("tags: travel\nweights:\n  Bandits: 2\nentries:\n  - Bandits\n  - Pilgrims\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1-2 Bandits", "3 Pilgrims"}, rowEntries(tables[0]))
+	assert.Equal(t, []string{"travel"}, tables[0].Metadata().Tags)
+
+	tables, err = ParseTables("Encounters.yml", []byte("columns: [Encounter, Number]\nrows:\n  - [1, Goblins, 2d4]\n  - [2, Rats, 3d6]\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1 Encounter: Goblins, Number: 2d4", "2 Encounter: Rats, Number: 3d6"}, rowEntries(tables[0]))
+
+	_, err = ParseTables("Nothing.yaml", []byte("description: no rows\n"))
+	assert.Error(t, err)
+}
+
+func TestParseTables_unknownExtension(t *testing.T) {
+	_, err := ParseTables("Names", []byte("# Names\n* Ada\n* Bo\n"))
+	assert.Error(t, err)
+}
+
+func TestRegisterFormat(t *testing.T) {
+	registered := formats
+	defer func() { formats = registered }()
+
+	assert.False(t, IsTableFile("Names.lines"))
+	RegisterFormat(Format{Name: "lines", Extensions: []string{".lines"}, Parse: func(content []byte, name string) ([]RollableTable, error) {
+		return []RollableTable{fromMDList(MDList{string(content)}, name, nil)}, nil
+	}})
+	assert.True(t, IsTableFile("Names.LINES"))
+	tables, err := ParseTables("Names.lines", []byte("Ada"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1 Ada"}, rowEntries(tables[0]))
+}
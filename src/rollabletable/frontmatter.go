@@ -0,0 +1,131 @@
+package rollabletable
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Metadata is what a table file's YAML frontmatter says about its tables, ie.
+//
+//	---
+//	dice: 2d6
+//	description: Who the party meets on the road
+//	tags: [encounters, travel]
+//	source: Road Guide p. 12
+//	rolls: 2
+//	---
+type Metadata struct {
+	Dice        string         `yaml:"dice"` // rolled instead of one die the size of the table, unless a table's header has dice
+	Description string         `yaml:"description"`
+	Tags        []string       `yaml:"-"`
+	Source      string         `yaml:"source"`
+	Credit      string         `yaml:"credit"`
+	Weights     map[string]int `yaml:"weights"` // how many times as likely each entry of a list is to be picked
+	Rolls       int            `yaml:"rolls"`   // how many times the table is rolled by default
+}
+
+// Metadata returns what the frontmatter of the table's file says about it
+func (rt RollableTable) Metadata() Metadata {
+	return rt.metadata
+}
+
+// splitFrontmatter separates the frontmatter between the '---' lines at the top of a document from the rest of it
+func splitFrontmatter(doc []string) (frontmatter []string, rest []string) {
+	if len(doc) == 0 || strings.TrimSpace(doc[0]) != "---" {
+		return nil, doc
+	}
+	for i := 1; i < len(doc); i++ {
+		if line := strings.TrimSpace(doc[i]); line == "---" || line == "..." {
+			return doc[1:i], doc[i+1:]
+		}
+	}
+	return nil, doc
+}
+
+// parseFrontmatter reads the metadata in the YAML lines of frontmatter, which start after line offset of file
+func parseFrontmatter(frontmatter []string, file string, offset int) (Metadata, error) {
+	var parsed struct {
+		Metadata `yaml:",inline"`
+		Tags     yaml.Node `yaml:"tags"` // Obsidian allows a single tag as well as a list of them
+	}
+	if err := yaml.Unmarshal([]byte(strings.Join(frontmatter, "\n")), &parsed); err != nil {
+		return Metadata{}, yamlError(file, offset, "frontmatter not parsable", err)
+	}
+	metadata := parsed.Metadata
+	switch parsed.Tags.Kind {
+	case yaml.ScalarNode:
+		metadata.Tags = []string{parsed.Tags.Value}
+	case yaml.SequenceNode:
+		if err := parsed.Tags.Decode(&metadata.Tags); err != nil {
+			return Metadata{}, &ParseError{File: file, Line: offset + parsed.Tags.Line, Reason: "frontmatter tags not parsable",
+				Hint: "give the tags as a list like '[encounters, travel]'"}
+		}
+	}
+	if metadata.Dice != "" {
+		if _, ok := parseDice(metadata.Dice); !ok {
+			return Metadata{}, &ParseError{File: file, Line: frontmatterLine(frontmatter, "dice", offset),
+				Reason: fmt.Sprintf("frontmatter dice '%s' not parsable", metadata.Dice), Hint: "write the dice like '2d6' or '1d8+1d4'"}
+		}
+		if len(metadata.Weights) > 0 {
+			// the weights size the die of a list, which the dice would replace
+			return Metadata{}, &ParseError{File: file, Line: frontmatterLine(frontmatter, "dice", offset),
+				Reason: "frontmatter has both dice and weights", Hint: "give either the dice or the weights, not both"}
+		}
+	}
+	return metadata, nil
+}
+
+// frontmatterLine finds the line of file that sets key in frontmatter, which starts after line offset, or 0 if
+// none does
+func frontmatterLine(frontmatter []string, key string, offset int) int {
+	for i, line := range frontmatter {
+		if strings.HasPrefix(line, key+":") {
+			return offset + i + 1
+		}
+	}
+	return 0
+}
+
+// applyMetadata gives a table what its file's frontmatter says about it. The frontmatter dice replace the die
+// of a table without dice in its header when it is the only table in the file, or when every roll of them
+// picks one of its rows and every row can be rolled. diceLine is the line of the file the dice are on.
+func (rt *RollableTable) applyMetadata(metadata Metadata, only bool, diceLine int) {
+	rt.metadata = metadata
+	dice, ok := parseDice(metadata.Dice)
+	if !ok || rt.headerDice {
+		return
+	}
+	if !rt.fits(dice) {
+		if !only {
+			return // meant for another table in the file
+		}
+		rt.warn(diceLine, 0, "number the rows to match the dice, or give the table its own dice in its header",
+			"frontmatter dice %s leave rows that can never be rolled or rolls that pick no row", dice)
+	}
+	rt.dice = dice
+}
+
+// fits reports whether every roll of dice picks one of the table's rows and every row can be rolled with them
+func (rt RollableTable) fits(dice DiceExpression) bool {
+	reached := make([]bool, len(rt.rows))
+	for _, result := range dice.Distribution().Results() {
+		found := false
+		for i, row := range rt.rows {
+			if row.contains(result) {
+				reached[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, ok := range reached {
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+package rollabletable
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const frontmatterDoc = `---
+dice: 1d[1,1,2]
+description: Who the party meets on the road
+tags: [encounters, travel]
+source: Road Guide p. 12
+credit: Sam
+rolls: 2
+aliases: [Road]
+---
+# Road Encounters
+* Bandits
+* Pilgrims
+`
+
+func Test_ParseRollableTable_frontmatter(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader(frontmatterDoc)), "Road.md")
+	assert.NoError(t, err)
+	assert.Equal(t, Metadata{
+		Dice:        "1d[1,1,2]",
+		Description: "Who the party meets on the road",
+		Tags:        []string{"encounters", "travel"},
+		Source:      "Road Guide p. 12",
+		Credit:      "Sam",
+		Rolls:       2,
+	}, table.Metadata())
+	assert.Equal(t, "1d[1,1,2]", table.dice.String())
+	assert.Equal(t, []string{"Bandits", "Pilgrims"}, []string{table.rows[0].Entry, table.rows[1].Entry})
+	assert.Empty(t, Validate(table, nil))
+	assert.Empty(t, table.Warnings())
+}
+
+func Test_ParseRollableTable_frontmatterKeepsHeaderDice(t *testing.T) {
+	doc := "---\ndice: 1d2\ntags: loot\n---\n| 2d4 | Loot |\n|---|---|\n| 2-5 | copper |\n| 6-8 | silver |\n"
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader(doc)), "Loot.md")
+	assert.NoError(t, err)
+	assert.Equal(t, "2d4", table.dice.String())
+	assert.Equal(t, []string{"loot"}, table.Metadata().Tags)
+}
+
+func Test_ParseRollableTable_badFrontmatter(t *testing.T) {
+	for _, doc := range []string{
+		"---\ndice: [unclosed\n---\n* foo\n",
+		"---\ndice: lots\n---\n* foo\n",
+		"---\ndice: 1d4\nweights:\n  foo: 3\n---\n* foo\n* bar\n",
+	} {
+		_, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader(doc)), "bad.md")
+		assert.Error(t, err, doc)
+	}
+}
+
+func Test_splitFrontmatter(t *testing.T) {
+	frontmatter, rest := splitFrontmatter([]string{"---", "dice: 2d6", "...", "* foo"})
+	assert.Equal(t, []string{"dice: 2d6"}, frontmatter)
+	assert.Equal(t, []string{"* foo"}, rest)
+
+	frontmatter, rest = splitFrontmatter([]string{"---", "* foo"})
+	assert.Nil(t, frontmatter)
+	assert.Equal(t, []string{"---", "* foo"}, rest)
+}
+
+func Test_ParseRollableTable_frontmatterWeights(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("---\nweights:\n  Bandits: 3\n---\n* Bandits\n* Pilgrims\n")), "Road.md")
+	assert.NoError(t, err)
+	assert.Equal(t, []Row{{Min: 1, Max: 3, Entry: "Bandits"}, {Min: 4, Max: 4, Entry: "Pilgrims"}}, table.rows)
+}
+
+func Test_ParseRollableTables_frontmatterDiceSections(t *testing.T) {
+	doc := "---\ndice: 2d6\n---\n## A\n* a\n* b\n## B\n* c\n## C\n| Roll | Weather |\n|---|---|\n| 2-6 | rain |\n| 7-12 | sun |\n"
+	tables, err := ParseRollableTables(*bufio.NewScanner(strings.NewReader(doc)), "Sections.md")
+	assert.NoError(t, err)
+	assert.Len(t, tables, 3)
+	assert.Equal(t, "1d2", tables[0].dice.String())
+	assert.Equal(t, "1d1", tables[1].dice.String())
+	assert.Equal(t, "2d6", tables[2].dice.String())
+	for _, table := range tables {
+		assert.Empty(t, Validate(table, nil), table.Name)
+		assert.Empty(t, table.Warnings(), table.Name)
+	}
+}
+
+func Test_ParseRollableTable_frontmatterDiceUnreachableRows(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("---\ndice: 2d6\n---\n* a\n* b\n* c\n")), "Unfit.md")
+	assert.NoError(t, err)
+	assert.Equal(t, "2d6", table.dice.String())
+	assert.Len(t, table.Warnings(), 1)
+	assert.Equal(t, 2, table.Warnings()[0].Line)
+}
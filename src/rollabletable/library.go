@@ -0,0 +1,363 @@
+package rollabletable
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Library is every table file under a directory. The directory is walked once and each file is parsed the
+// first time one of its tables is needed, so rolling a table with many links doesn't read the same files
+// again. A Library is safe to use from more than one goroutine.
+type Library struct {
+	MaxDepth int // how many links deep a roll may go, DefaultMaxDepth unless changed
+
+	root  string
+	paths []string // every table file under root, in the order they are walked
+
+	mu     sync.Mutex
+	parsed map[string]parsedFile
+}
+
+// repeatedLink matches what comes before a link rolled more than once, ie. the '3x' of '3x[[Weapons]]' or the
+// '2x unique ' of '2x unique [[Names]]'. Group 1 is the count, a number or dice, and group 2 is there when the
+// entries picked must all be different.
+var repeatedLink = regexp.MustCompile(`(?:^|\s)(\S+)x( unique)? ?$`)
+
+// variablePattern matches the start of setting a variable, ie. '{$base =' of '{$base = [[Animals]]}', or using
+// one, ie. '{$base}'. Group 1 is the name and group 2 is either '=' or '}'.
+var variablePattern = regexp.MustCompile(`\{\$(\w+)\s*(=|\})`)
+
+// DefaultMaxDepth is deeper than any chain of tables written on purpose, so only a mistake reaches it
+const DefaultMaxDepth = 32
+
+type parsedFile struct {
+	tables []RollableTable
+	err    error
+}
+
+// NewLibrary indexes the table files under root, leaving out directories starting with a '.' like '.obsidian'
+func NewLibrary(root string) (*Library, error) {
+	library := &Library{MaxDepth: DefaultMaxDepth, root: root, parsed: map[string]parsedFile{}}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && strings.HasPrefix(d.Name(), ".") && path != root {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && IsTableFile(path) {
+			library.paths = append(library.paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return library, nil
+}
+
+// Paths returns every table file in the library
+func (l *Library) Paths() []string {
+	return append([]string{}, l.paths...)
+}
+
+// Find returns the table files whose path contains query, ignoring case, ie. 'weapons' finds 'Items/Weapons.md'
+func (l *Library) Find(query string) ([]string, error) {
+	query = normalizeQuery(query)
+	if query == "" {
+		return nil, fmt.Errorf("Please provide a table name")
+	}
+	var paths []string
+	for _, path := range l.paths {
+		if strings.Contains(strings.ToLower(path), query) {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("Table not found: %s", query)
+	}
+	return paths, nil
+}
+
+// File returns every table in a file of the library, parsing it only the first time
+func (l *Library) File(path string) ([]RollableTable, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if parsed, ok := l.parsed[path]; ok {
+		return parsed.tables, parsed.err
+	}
+	content, err := os.ReadFile(path)
+	var tables []RollableTable
+	if err == nil {
+		tables, err = ParseTables(path, content)
+	}
+	l.parsed[path] = parsedFile{tables: tables, err: err}
+	return tables, err
+}
+
+// Resolve finds the table a link in the table named from leads to, the way Obsidian does. The link is a path
+// from the folder of the linking file first, then the end of a path anywhere in the library, which only one file
+// may have. The link can name a heading or column after a '#', ie. 'Dungeon#Traps', or only a heading of the
+// same file, ie. '#Traps'.
+func (l *Library) Resolve(link string, from string) (RollableTable, error) {
+	query, fragment, _ := strings.Cut(link, "#")
+	path, _, _ := strings.Cut(from, "#")
+	if query != "" {
+		var err error
+		if path, err = l.linkedPath(query, path); err != nil {
+			return RollableTable{}, err
+		}
+	}
+	tables, err := l.File(path)
+	if err != nil {
+		return RollableTable{}, err
+	}
+	return Select(tables, fragment)
+}
+
+// linkedPath finds the file a link in the file at from leads to. A link starting with '/' leads from the root of
+// the library instead.
+func (l *Library) linkedPath(link string, from string) (string, error) {
+	if unescaped, err := url.PathUnescape(link); err == nil {
+		link = unescaped // markdown links escape spaces and the like, ie. 'Ethereal%20Forms.md'
+	}
+	link = normalizeQuery(link)
+	relative := strings.ToLower(filepath.Join(filepath.Dir(from), link))
+	if rooted := strings.TrimPrefix(link, string(filepath.Separator)); rooted != link {
+		relative = strings.ToLower(filepath.Join(l.root, rooted))
+	}
+	var matches []string
+	for _, path := range l.paths {
+		names := linkNames(strings.ToLower(path))
+		if names[0] == relative || names[1] == relative {
+			return path, nil
+		}
+		if rel, err := filepath.Rel(l.root, path); err == nil {
+			names = linkNames(strings.ToLower(rel))
+		}
+		for _, name := range names {
+			if name == link || strings.HasSuffix(name, string(filepath.Separator)+link) {
+				matches = append(matches, path)
+				break
+			}
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("Table not found: %s", link)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("Link %s is ambiguous, it could be %s; write more of the path to pick one", link, strings.Join(matches, " or "))
+}
+
+// linkNames returns the ways a link can name the file at path, with and without its extension
+func linkNames(path string) [2]string {
+	return [2]string{path, strings.TrimSuffix(path, filepath.Ext(path))}
+}
+
+// LinkExists reports whether a link in the table named from leads to a table, for Validate
+func (l *Library) LinkExists(link string, from string) bool {
+	_, err := l.Resolve(link, from)
+	return err == nil
+}
+
+// Roll rolls on the table and replaces every link in the result with a roll on the table it leads to
+func (l *Library) Roll(table RollableTable, source RandomSource) (string, error) {
+	result, err := l.Explain(table, source)
+	return result.Result, err
+}
+
+// Explain rolls on the table and on every table linked from the result, recording each roll. Dice in the
+// result, like the 2d6 of '2d6 goblins', are rolled along the way, and variables are set and written in. A link
+// that can't be followed, leads back to a table already rolled on or goes more than MaxDepth links deep is a
+// *LinkError.
+func (l *Library) Explain(table RollableTable, source RandomSource) (RollResult, error) {
+	return l.explain(table, rollContext{source: source, chain: []string{table.Name}, variables: map[string]string{}})
+}
+
+// rollContext is what a roll passes on to the rolls on the tables it links to
+type rollContext struct {
+	source    RandomSource
+	chain     []string          // the tables rolled on to reach this one, ending with it
+	variables map[string]string // set by '{$name = ...}', shared by every table of the roll
+}
+
+// explain rolls on the table, which was reached through the tables in the context's chain
+func (l *Library) explain(table RollableTable, ctx rollContext) (RollResult, error) {
+	return l.expand(table.Explain(ctx.source), table, ctx)
+}
+
+// expand rolls the dice, variables and links in a result of rolling on the table
+func (l *Library) expand(result RollResult, table RollableTable, ctx rollContext) (RollResult, error) {
+	expanded, err := l.expandVariables(result.Result, table, ctx, &result)
+	result.Result = expanded
+	return result, err
+}
+
+// expandVariables sets the variables in text, ie. '{$base = [[Animals]]}', which writes nothing, and writes in
+// the ones used, ie. '{$base}'. The text around them is expanded by expandLinks, and each variable is set and
+// used in the order it is written, including by the tables linked from it.
+func (l *Library) expandVariables(text string, table RollableTable, ctx rollContext, result *RollResult) (string, error) {
+	expanded := strings.Builder{}
+	for {
+		variable := variablePattern.FindStringSubmatchIndex(text)
+		if variable == nil {
+			break
+		}
+		before, err := l.expandLinks(text[:variable[0]], table, ctx, result)
+		expanded.WriteString(before)
+		if err != nil {
+			return expanded.String(), err
+		}
+
+		name := text[variable[2]:variable[3]]
+		if text[variable[4]:variable[5]] == "}" {
+			value, ok := ctx.variables[name]
+			if !ok {
+				return expanded.String(), fmt.Errorf("%s: $%s is used before it is set", table.Name, name)
+			}
+			expanded.WriteString(value)
+			text = text[variable[1]:]
+			continue
+		}
+		end := closingBrace(text, variable[1])
+		if end < 0 {
+			return expanded.String(), fmt.Errorf("%s: '{$%s =' has no closing '}'", table.Name, name)
+		}
+		value, err := l.expandVariables(strings.TrimSpace(text[variable[1]:end]), table, ctx, result)
+		if err != nil {
+			return expanded.String(), err
+		}
+		ctx.variables[name] = value
+		text = text[end+1:]
+	}
+	rest, err := l.expandLinks(text, table, ctx, result)
+	expanded.WriteString(rest)
+	return expanded.String(), err
+}
+
+// expandLinks replaces the links in text by rolls on the tables they lead to, and rolls the dice between them
+func (l *Library) expandLinks(text string, table RollableTable, ctx rollContext, result *RollResult) (string, error) {
+	expanded := strings.Builder{}
+	rollText := func(text string) {
+		rolled, dice := rollInlineDice(text, ctx.source)
+		expanded.WriteString(rolled)
+		result.Inline = append(result.Inline, dice...)
+	}
+	last := 0
+	for _, link := range LinkPattern.FindAllStringSubmatchIndex(text, -1) {
+		before, count, distinct := text[last:link[0]], 1, false
+		if repeat := repeatedLink.FindStringSubmatchIndex(before); repeat != nil {
+			if rolled, dice, ok := linkCount(before[repeat[2]:repeat[3]], ctx.source); ok {
+				count, distinct = rolled, repeat[4] >= 0
+				result.Inline = append(result.Inline, dice...)
+				before = before[:repeat[2]]
+			}
+		}
+		rollText(before)
+		last = link[1]
+		linkedResults, err := l.follow(text[link[4]:link[5]], count, distinct, table, ctx)
+		if err != nil {
+			return expanded.String(), err
+		}
+		var rolled []string
+		for _, linkedResult := range linkedResults {
+			result.Nested = append(result.Nested, linkedResult)
+			rolled = append(rolled, linkedResult.Result)
+		}
+		expanded.WriteString(joinList(rolled))
+	}
+	rollText(text[last:])
+	return expanded.String(), nil
+}
+
+// follow rolls count times on the table a link in table leads to, never picking the same entry twice when distinct
+func (l *Library) follow(link string, count int, distinct bool, table RollableTable, ctx rollContext) ([]RollResult, error) {
+	linked, err := l.Resolve(link, table.Name)
+	if err != nil {
+		return nil, &LinkError{Chain: extendChain(ctx.chain, link), Err: err}
+	}
+	next := ctx
+	next.chain = extendChain(ctx.chain, linked.Name)
+	for _, name := range ctx.chain {
+		if name == linked.Name {
+			return nil, &LinkError{Chain: next.chain, Err: ErrLinkCycle}
+		}
+	}
+	if len(ctx.chain) > l.MaxDepth {
+		return nil, &LinkError{Chain: next.chain, Err: ErrLinkDepth}
+	}
+
+	var results []RollResult
+	if distinct {
+		if results, err = linked.ExplainDistinct(count, ctx.source); err != nil {
+			return nil, &LinkError{Chain: next.chain, Err: err}
+		}
+	} else {
+		results = linked.ExplainTimes(count, ctx.source)
+	}
+	for i := range results {
+		if results[i], err = l.expand(results[i], linked, next); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// closingBrace finds the '}' closing a '{' opened just before start, skipping any pairs of braces inside it
+func closingBrace(text string, start int) int {
+	depth := 0
+	for i := start; i < len(text); i++ {
+		switch text[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// linkCount reads how many times a link is rolled, either a number or dice like '1d4' or '{1d4+1}'
+func linkCount(count string, source RandomSource) (int, []DiceRoll, bool) {
+	if n, err := strconv.Atoi(count); err == nil {
+		return n, nil, true
+	}
+	expression, ok := parseDice(strings.TrimSuffix(strings.TrimPrefix(count, "{"), "}"))
+	if !ok {
+		return 0, nil, false
+	}
+	n, dice := expression.Explain(source)
+	return n, dice, true
+}
+
+// joinList writes a list the way it would be said, ie. 'sword, axe and bow'
+func joinList(items []string) string {
+	if len(items) < 2 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
+
+// extendChain copies chain with name added, so the chains of sibling links don't share an array
+func extendChain(chain []string, name string) []string {
+	return append(append([]string{}, chain...), name)
+}
+
+// normalizeQuery makes a table name or link comparable with the paths of the library
+func normalizeQuery(query string) string {
+	query = strings.TrimPrefix(query, "./")
+	query = strings.TrimPrefix(query, ".\\")
+	return strings.ToLower(filepath.FromSlash(query))
+}
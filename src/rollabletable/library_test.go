@@ -0,0 +1,319 @@
+package rollabletable
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeLibrary writes files, keyed by their slash-separated paths, into a new directory and indexes it
+func writeLibrary(t *testing.T, files map[string]string) (*Library, string) {
+	root := t.TempDir()
+	for path, content := range files {
+		path = filepath.Join(root, filepath.FromSlash(path))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	library, err := NewLibrary(root)
+	assert.NoError(t, err)
+	return library, root
+}
+
+var monsterFiles = map[string]string{
+	"Monsters.md":         "* [[Animals]] with [Features](Parts/Features.md)\n",
+	"Animals.md":          "## Land\n* Wolf\n* Bear\n\n## Sea\n* Shark\n* [[#Land]] that swims\n",
+	"Parts/Features.md":   "| d4 | Feature | Size |\n|---|---|---|\n| 1-2 | horns | big |\n| 3-4 | wings | small |\n",
+	"Parts/notes.txt":     "not a table",
+	".obsidian/Hidden.md": "* hidden\n",
+}
+
+func TestNewLibrary(t *testing.T) {
+	library, root := writeLibrary(t, monsterFiles)
+	assert.Equal(t, []string{
+		filepath.Join(root, "Animals.md"),
+		filepath.Join(root, "Monsters.md"),
+		filepath.Join(root, "Parts", "Features.md"),
+	}, library.Paths())
+
+	_, err := NewLibrary(filepath.Join(root, "missing"))
+	assert.Error(t, err)
+}
+
+func TestLibrary_Find(t *testing.T) {
+	library, root := writeLibrary(t, monsterFiles)
+	paths, err := library.Find("FEATURES")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(root, "Parts", "Features.md")}, paths)
+
+	paths, err = library.Find("./parts/features.md")
+	assert.NoError(t, err)
+	assert.Len(t, paths, 1)
+
+	_, err = library.Find("Hidden")
+	assert.Error(t, err)
+	_, err = library.Find("")
+	assert.Error(t, err)
+}
+
+func TestLibrary_File(t *testing.T) {
+	library, root := writeLibrary(t, monsterFiles)
+	path := filepath.Join(root, "Animals.md")
+	tables, err := library.File(path)
+	assert.NoError(t, err)
+	assert.Len(t, tables, 2)
+
+	assert.NoError(t, os.WriteFile(path, []byte("* changed\n"), 0o644))
+	cached, err := library.File(path)
+	assert.NoError(t, err)
+	assert.Equal(t, tables, cached)
+
+	_, err = library.File(filepath.Join(root, "Parts", "notes.txt"))
+	assert.Error(t, err)
+}
+
+func TestLibrary_Resolve(t *testing.T) {
+	library, root := writeLibrary(t, monsterFiles)
+	animals := filepath.Join(root, "Animals.md")
+
+	table, err := library.Resolve("Animals#Sea", filepath.Join(root, "Monsters.md"))
+	assert.NoError(t, err)
+	assert.Equal(t, animals+"#Sea", table.Name)
+
+	table, err = library.Resolve("#Land", animals+"#Sea")
+	assert.NoError(t, err)
+	assert.Equal(t, animals+"#Land", table.Name)
+
+	table, err = library.Resolve("Parts/Features.md#Size", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "small", table.RollWith(NewScriptedSource(3)))
+
+	_, err = library.Resolve("Plants", animals)
+	assert.Error(t, err)
+	assert.False(t, library.LinkExists("Animals#Air", animals))
+	assert.True(t, library.LinkExists("#Sea", animals))
+}
+
+func TestLibrary_Resolve_relative(t *testing.T) {
+	library, root := writeLibrary(t, map[string]string{
+		"Magic/Spell.md":         "* [EtherealForms](EtherealForms)\n",
+		"Magic/EtherealForms.md": "* mist\n",
+		"Items/EtherealForms.md": "* ghost blade\n",
+		"Items/Weapons.md":       "* sword\n",
+	})
+	spell := filepath.Join(root, "Magic", "Spell.md")
+
+	table, err := library.Resolve("EtherealForms", spell)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "Magic", "EtherealForms.md"), table.Name)
+
+	table, err = library.Resolve("../Items/EtherealForms.md", spell)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "Items", "EtherealForms.md"), table.Name)
+
+	table, err = library.Resolve("items/weapons", spell)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "Items", "Weapons.md"), table.Name)
+
+	_, err = library.Resolve("EtherealForms", filepath.Join(root, "Items", "Weapons.md")+"#Weapons")
+	assert.NoError(t, err)
+	_, err = library.Resolve("EtherealForms", "")
+	assert.EqualError(t, err, "Link etherealforms is ambiguous, it could be "+
+		filepath.Join(root, "Items", "EtherealForms.md")+" or "+filepath.Join(root, "Magic", "EtherealForms.md")+
+		"; write more of the path to pick one")
+
+	// Only whole names match, not a part of one
+	_, err = library.Resolve("Weapon", spell)
+	assert.EqualError(t, err, "Table not found: weapon")
+	_, err = library.Resolve("Forms", spell)
+	assert.Error(t, err)
+}
+
+func TestLibrary_Resolve_escapedAndRooted(t *testing.T) {
+	library, root := writeLibrary(t, map[string]string{
+		"Magic/Spell.md":          "* [Ethereal Forms](Ethereal%20Forms.md)\n",
+		"Magic/Ethereal Forms.md": "* mist\n",
+		"Magic/Elements.md":       "* fire\n",
+		"Old/Magic/Elements.md":   "* aether\n",
+	})
+	spell := filepath.Join(root, "Magic", "Spell.md")
+
+	table, err := library.Resolve("Ethereal%20Forms.md", spell)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "Magic", "Ethereal Forms.md"), table.Name)
+
+	table, err = library.Resolve("/Magic/Elements.md", filepath.Join(root, "Old", "Magic", "Elements.md"))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "Magic", "Elements.md"), table.Name)
+	table, err = library.Resolve("/Old/Magic/Elements", spell)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "Old", "Magic", "Elements.md"), table.Name)
+
+	_, err = library.Resolve("/Elements.md", spell)
+	assert.Error(t, err)
+}
+
+func TestLibrary_Explain(t *testing.T) {
+	library, root := writeLibrary(t, monsterFiles)
+	tables, err := library.File(filepath.Join(root, "Monsters.md"))
+	assert.NoError(t, err)
+
+	// Animals is its first table, Land, which picks Bear, and Features picks wings
+	result, err := library.Explain(tables[0], NewScriptedSource(0, 1, 2))
+	assert.NoError(t, err)
+	assert.Equal(t, "Bear with Feature: wings, Size: small", result.Result)
+	assert.Len(t, result.Nested, 2)
+	assert.Equal(t, filepath.Join(root, "Animals.md")+"#Land", result.Nested[0].Table)
+
+	rolled, err := library.Roll(tables[0], NewScriptedSource(0, 1, 2))
+	assert.NoError(t, err)
+	assert.Equal(t, result.Result, rolled)
+}
+
+func TestLibrary_Explain_inlineDice(t *testing.T) {
+	library, root := writeLibrary(t, map[string]string{
+		"Hoard.md":  "* {1d4+1} [[Coins]] and a [d6 sword](Swords)\n",
+		"Coins.md":  "* 2d6 gold\n",
+		"Swords.md": "* rusty\n",
+	})
+	tables, err := library.File(filepath.Join(root, "Hoard.md"))
+	assert.NoError(t, err)
+	// the hoard's 1d4 rolls before the coins' 2d6, and the d6 in the link's label isn't rolled
+	result, err := library.Explain(tables[0], NewScriptedSource(0, 2, 0, 1, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, "4 3 gold and a rusty", result.Result)
+	assert.Equal(t, []DiceRoll{{Dice: "1d4", Result: DieResult{3}, Total: 3}}, result.Inline)
+	assert.Equal(t, []DiceRoll{{Dice: "2d6", Result: DieResult{2, 1}, Total: 3}}, result.Nested[0].Inline)
+}
+
+func TestLibrary_Explain_repeatedLinks(t *testing.T) {
+	library, root := writeLibrary(t, map[string]string{
+		"Hoard.md":   "* 3x[[Weapons]]\n* found 1d4x[Weapons](Weapons) in a box\n* 2x unique [[Weapons]]\n* 4x unique [[Weapons]]\n",
+		"Weapons.md": "* sword\n* axe\n* bow\n",
+	})
+	tables, err := library.File(filepath.Join(root, "Hoard.md"))
+	assert.NoError(t, err)
+	hoard := tables[0]
+
+	result, err := library.Explain(hoard, NewScriptedSource(0, 0, 0, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, "sword, sword and axe", result.Result)
+	assert.Len(t, result.Nested, 3)
+
+	result, err = library.Explain(hoard, NewScriptedSource(1, 1, 2, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, "found bow and axe in a box", result.Result)
+	assert.Equal(t, []DiceRoll{{Dice: "1d4", Result: DieResult{2}, Total: 2}}, result.Inline)
+
+	// the sword already picked is left out of the second draw
+	result, err = library.Explain(hoard, NewScriptedSource(2, 0, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, "sword and bow", result.Result)
+
+	_, err = library.Explain(hoard, NewScriptedSource(3))
+	assert.ErrorContains(t, err, "4 different entries can't be picked from a table with 3")
+
+	// the 7+ row can never be rolled, so only one entry can be picked
+	library, root = writeLibrary(t, map[string]string{
+		"Pair.md":    "* 2x unique [[Highest]]\n",
+		"Highest.md": "| 1d6!kh1 | result |\n|---|---|\n| 1-6 | low |\n| 7+ | high |\n",
+	})
+	tables, err = library.File(filepath.Join(root, "Pair.md"))
+	assert.NoError(t, err)
+	_, err = library.Explain(tables[0], NewSeededSource(1))
+	assert.ErrorContains(t, err, "2 different entries can't be picked from a table with 1")
+}
+
+func TestLibrary_Explain_variables(t *testing.T) {
+	library, root := writeLibrary(t, map[string]string{
+		"Person.md":   "* {$gender = [[Gender]]}[[Names]], {$age = {1d4+1}0}{$age} years old, [[Pronouns]] ({$gender}, {$age})\n",
+		"Gender.md":   "* female\n* male\n",
+		"Names.md":    "* {$name = Ada}{$name}\n",
+		"Pronouns.md": "* {$gender} pronouns, called {$name} by friends\n",
+		"Broken.md":   "* {$missing} and more\n",
+		"Unclosed.md": "* {$base = [[Gender]]\n",
+	})
+	tables, err := library.File(filepath.Join(root, "Person.md"))
+	assert.NoError(t, err)
+	result, err := library.Explain(tables[0], NewScriptedSource(0, 1, 0, 2, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, "Ada, 40 years old, male pronouns, called Ada by friends (male, 40)", result.Result)
+	assert.Len(t, result.Nested, 3)
+
+	// each roll starts without variables
+	_, err = library.Explain(tables[0], NewScriptedSource(0, 0, 0, 0, 0))
+	assert.NoError(t, err)
+
+	tables, err = library.File(filepath.Join(root, "Broken.md"))
+	assert.NoError(t, err)
+	_, err = library.Explain(tables[0], NewScriptedSource(0))
+	assert.EqualError(t, err, filepath.Join(root, "Broken.md")+": $missing is used before it is set")
+
+	tables, err = library.File(filepath.Join(root, "Unclosed.md"))
+	assert.NoError(t, err)
+	_, err = library.Explain(tables[0], NewScriptedSource(0))
+	assert.ErrorContains(t, err, "'{$base =' has no closing '}'")
+}
+
+func TestLibrary_Explain_missingLink(t *testing.T) {
+	library, root := writeLibrary(t, map[string]string{"Broken.md": "* [[Nowhere]]\n"})
+	tables, err := library.File(filepath.Join(root, "Broken.md"))
+	assert.NoError(t, err)
+	_, err = library.Explain(tables[0], NewScriptedSource(0))
+	assert.EqualError(t, err, filepath.Join(root, "Broken.md")+" -> Nowhere: Table not found: nowhere")
+}
+
+func TestLibrary_Explain_cycle(t *testing.T) {
+	library, root := writeLibrary(t, map[string]string{
+		"Self.md":  "* [[Self]] again\n",
+		"Ping.md":  "* [[Pong]]\n",
+		"Pong.md":  "* [[Ping]]\n",
+		"Start.md": "* [[Ping]]\n",
+	})
+	ping, pong := filepath.Join(root, "Ping.md"), filepath.Join(root, "Pong.md")
+
+	tables, err := library.File(filepath.Join(root, "Self.md"))
+	assert.NoError(t, err)
+	_, err = library.Explain(tables[0], NewScriptedSource(0))
+	assert.ErrorIs(t, err, ErrLinkCycle)
+
+	tables, err = library.File(filepath.Join(root, "Start.md"))
+	assert.NoError(t, err)
+	_, err = library.Explain(tables[0], NewScriptedSource(0))
+	var linkErr *LinkError
+	assert.ErrorAs(t, err, &linkErr)
+	assert.Equal(t, []string{filepath.Join(root, "Start.md"), ping, pong, ping}, linkErr.Chain)
+	assert.EqualError(t, err, filepath.Join(root, "Start.md")+" -> "+ping+" -> "+pong+" -> "+ping+": links back to a table it was rolled from")
+}
+
+func TestLibrary_Explain_maxDepth(t *testing.T) {
+	library, root := writeLibrary(t, map[string]string{
+		"A.md": "* [[B]]\n",
+		"B.md": "* [[C]]\n",
+		"C.md": "* end\n",
+	})
+	tables, err := library.File(filepath.Join(root, "A.md"))
+	assert.NoError(t, err)
+	library.MaxDepth = 2
+	result, err := library.Explain(tables[0], NewScriptedSource(0))
+	assert.NoError(t, err)
+	assert.Equal(t, "end", result.Result)
+
+	library.MaxDepth = 1
+	_, err = library.Explain(tables[0], NewScriptedSource(0))
+	assert.ErrorIs(t, err, ErrLinkDepth)
+	var linkErr *LinkError
+	assert.ErrorAs(t, err, &linkErr)
+	assert.Len(t, linkErr.Chain, 3)
+}
+
+func TestLinkPattern(t *testing.T) {
+	link := LinkPattern.FindStringSubmatch("foo [text label](path/to/file) bar")
+	assert.Equal(t, "[text label](path/to/file)", link[0])
+	assert.Equal(t, "path/to/file", link[2])
+
+	internalLink := LinkPattern.FindStringSubmatch("foo [[path/to/file]] bar")
+	assert.Equal(t, "[[path/to/file]]", internalLink[0])
+	assert.Equal(t, "path/to/file", internalLink[2])
+}
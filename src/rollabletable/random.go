@@ -0,0 +1,59 @@
+package rollabletable
+
+import (
+	cryptorand "crypto/rand"
+	"math/big"
+	"math/rand"
+)
+
+// RandomSource supplies the randomness for every roll. Intn returns a number in [0,n), the same as
+// math/rand, so a *rand.Rand can be used directly.
+type RandomSource interface {
+	Intn(n int) int
+}
+
+// globalSource uses the top level math/rand functions and is used by Roll
+type globalSource struct{}
+
+func (gs globalSource) Intn(n int) int {
+	return rand.Intn(n)
+}
+
+var defaultSource RandomSource = globalSource{}
+
+// NewSeededSource returns a pseudo-random source that repeats the same rolls for the same seed
+func NewSeededSource(seed int64) RandomSource {
+	return rand.New(rand.NewSource(seed))
+}
+
+// CryptoSource draws from crypto/rand for rolls that can't be predicted
+type CryptoSource struct{}
+
+func (cs CryptoSource) Intn(n int) int {
+	v, err := cryptorand.Int(cryptorand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return rand.Intn(n)
+	}
+	return int(v.Int64())
+}
+
+// ScriptedSource returns its values in order, wrapping around at the end, which makes rolls predictable in
+// tests. Values are zero based like Intn, so a d6 landing on 4 is scripted as 3. A value too large for n
+// is taken modulo n.
+type ScriptedSource struct {
+	values []int
+	next   int
+}
+
+func NewScriptedSource(values ...int) *ScriptedSource {
+	return &ScriptedSource{values: values}
+}
+
+func (ss *ScriptedSource) Intn(n int) int {
+	if len(ss.values) == 0 {
+		return 0
+	}
+	v := ss.values[ss.next%len(ss.values)]
+	ss.next++
+	return ((v % n) + n) % n
+}
@@ -0,0 +1,45 @@
+package rollabletable
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ScriptedSource(t *testing.T) {
+	source := NewScriptedSource(3, 0, 7)
+	assert.Equal(t, 3, source.Intn(6))
+	assert.Equal(t, 0, source.Intn(6))
+	assert.Equal(t, 1, source.Intn(6))
+	assert.Equal(t, 3, source.Intn(6))
+	assert.Equal(t, 0, NewScriptedSource().Intn(6))
+}
+
+func Test_NewSeededSource(t *testing.T) {
+	dice, _ := parseDiceFromString("10d20")
+	assert.Equal(t, dice.RollWith(NewSeededSource(42)), dice.RollWith(NewSeededSource(42)))
+}
+
+func Test_CryptoSource(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		v := CryptoSource{}.Intn(6)
+		assert.GreaterOrEqual(t, v, 0)
+		assert.Less(t, v, 6)
+	}
+}
+
+func TestDice_RollWith(t *testing.T) {
+	dice, _ := parseDiceFromString("4d6kh3+1")
+	assert.Equal(t, 14, dice.RollWith(NewScriptedSource(0, 5, 3, 2)))
+
+	dice, _ = parseDiceFromString("2d6!")
+	assert.Equal(t, 6+6+2+4, dice.RollWith(NewScriptedSource(5, 5, 1, 3)))
+
+	dice, _ = parseDiceFromString("d%")
+	assert.Equal(t, 100, dice.RollWith(NewScriptedSource(9, 9)))
+}
+
+func TestRollableTable_RollWith(t *testing.T) {
+	table := fromMDList(MDList{"foo", "bar", "baz"}, "scripted", nil)
+	assert.Equal(t, "bar", table.RollWith(NewScriptedSource(1)))
+}
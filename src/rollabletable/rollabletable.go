@@ -4,97 +4,466 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 var (
-	rowRangePattern  = regexp.MustCompile(`(\d+)[-|–](\d+)`)     // matches roll ranges like '5-12' and captures the numbers as groups
-	markdownListItem = regexp.MustCompile(`^(\d+\. |\* |- |– )`) // identifies a line as a markdown list item, ie. '1. ' or '* '
+	rowRangePattern        = regexp.MustCompile(`^(-?\d+) *[-–—] *(-?\d+)$`)                   // matches roll ranges like '5-12', '96–00' or '-4--2' and captures the numbers as groups
+	rollCellPrefix         = regexp.MustCompile(`^(?:[\d\s,+≤<=–—-]|\bor\b)+`)                 // matches the roll at the start of a cell, leaving out text after it like ' (rare)'
+	markdownEmphasis       = regexp.MustCompile("[*_~`]+")                                     // matches markdown emphasis like the '**' of '**1-2**'
+	rollListSeparator      = regexp.MustCompile(` *(?:,|\bor\b) *`)                            // separates the ranges of a roll like '1, 3, 5' or '5 or 6'
+	openRangePattern       = regexp.MustCompile(`^(?:(-?\d+) ?\+|(?:≤|<=) ?(-?\d+))$`)         // matches open-ended ranges like '19+' or '≤2'
+	markdownTableSeparator = regexp.MustCompile(`^\s*:?-+:?\s*$`)                              // identifies a cell as part of the line under a markdown table's header, ie. ':---'
+	markdownHeading        = regexp.MustCompile(`^#{1,6}\s+(.+?)[\s#]*$`)                      // identifies a line as a markdown heading, ie. '## Traps', and captures its text
+	markdownListItem       = regexp.MustCompile(`^(\d+\. |\* |- |– )`)                         // identifies a line as a markdown list item, ie. '1. ' or '* '
+	listItemWeight         = regexp.MustCompile(`\s*(?:\(x([1-9]\d*)\)|\{w=([1-9]\d*)\})\s*$`) // matches a weight at the end of a list item, ie. '(x3)' or '{w=3}', and captures it
 )
 
 type RollableTable struct {
-	Name  string
-	table map[int]string
-	max   int
-	dice  Dice
+	Name    string
+	rows    []Row
+	max     int
+	dice    DiceExpression
+	columns []string // names of the result columns of a table with more than one
+	heading string   // the heading the table is under in its file
+
+	headerDice bool // the dice were given in the table's header rather than sized to the table
+	metadata   Metadata
+	warnings   []*ParseError
+}
+
+// Row is an entry of a table and the range of results that pick it. Open-ended rows like '19+' or '≤2'
+// use math.MaxInt or math.MinInt for their open end.
+type Row struct {
+	Min, Max int
+	Entry    string
+	Fields   []string       // every result column of a table with more than one, Entry names each of them
+	Subtable *RollableTable // a sub-list indented under a list entry, rolled on whenever the entry is picked
+
+	group int // shared by the rows a roll like '1, 3, 5' is split into, 0 for a row written on its own
+}
+
+func (r Row) contains(result int) bool {
+	return r.Min <= result && result <= r.Max
+}
+
+// Range writes the row's range the way it appears in a table, ie. '3', '3-7', '19+' or '≤2'
+func (r Row) Range() string {
+	switch {
+	case r.Max == math.MaxInt:
+		return strconv.Itoa(r.Min) + "+"
+	case r.Min == math.MinInt:
+		return "≤" + strconv.Itoa(r.Max)
+	case r.Min == r.Max:
+		return strconv.Itoa(r.Min)
+	}
+	return strconv.Itoa(r.Min) + "-" + strconv.Itoa(r.Max)
+}
+
+// Rows returns the rows of the table in the order they are written
+func (rt RollableTable) Rows() []Row {
+	return append([]Row{}, rt.rows...)
+}
+
+// find returns the row a result picks, the first one listed if rows overlap
+func (rt RollableTable) find(result int) (Row, bool) {
+	for _, row := range rt.rows {
+		if row.contains(result) {
+			return row, true
+		}
+	}
+	return Row{}, false
+}
+
+// Columns returns the names of the result columns of a table with more than one
+func (rt RollableTable) Columns() []string {
+	return append([]string{}, rt.columns...)
+}
+
+// Field returns the named column of a row rolled from rt
+func (rt RollableTable) Field(row Row, column string) (string, bool) {
+	for i, name := range rt.columns {
+		if strings.EqualFold(name, strings.TrimSpace(column)) {
+			if i < len(row.Fields) {
+				return row.Fields[i], true
+			}
+			return "", true
+		}
+	}
+	return "", false
+}
+
+// Column returns a table that rolls only one column of rt, ie. 'Reaction' from an encounter table
+func (rt RollableTable) Column(column string) (RollableTable, error) {
+	if _, ok := rt.Field(Row{}, column); !ok {
+		return RollableTable{}, fmt.Errorf("Column not found: %s has no column %s", rt.Name, column)
+	}
+	columnTable := rt
+	columnTable.Name = rt.Name + "#" + strings.TrimSpace(column)
+	columnTable.columns = nil
+	columnTable.rows = nil
+	for _, row := range rt.rows {
+		entry, _ := rt.Field(row, column)
+		columnTable.rows = append(columnTable.rows, Row{Min: row.Min, Max: row.Max, Entry: entry})
+	}
+	return columnTable, nil
 }
 
 func (rt RollableTable) Roll() string {
-	result := rt.dice.Roll()
+	return rt.RollWith(defaultSource)
+}
 
-	index, err := strconv.Atoi(rt.table[result])
-	if err != nil {
-		return rt.table[result]
+func (rt RollableTable) RollWith(source RandomSource) string {
+	return rt.Explain(source).Result
+}
+
+// RollResult records how a roll on a table was made, from the dice up to the entry picked
+type RollResult struct {
+	Table  string
+	Dice   []DiceRoll // every dice term rolled for the table's dice expression
+	Total  int        // the result of the table's dice expression
+	Row    Row        // the row picked, or the zero Row if the total isn't in the table
+	Missed bool       // the total isn't in any row of the table
+	Result string     // the entry once everything in it has been rolled, set by whatever expands it
+	Inline []DiceRoll // every dice term rolled inside the entry, ie. the 2d6 of '2d6 goblins'
+	Nested []RollResult
+}
+
+// Explain rolls on the table and records every step of the roll. When the row picked has a sub-list, the
+// entry rolled from it follows the row's entry, ie. 'Sword rusty'.
+func (rt RollableTable) Explain(source RandomSource) RollResult {
+	total, dice := rt.dice.Explain(source)
+	return rt.explainTotal(total, dice, source)
+}
+
+// explainTotal picks the row for a total of the table's dice and rolls on the row's subtable if it has one
+func (rt RollableTable) explainTotal(total int, dice []DiceRoll, source RandomSource) RollResult {
+	row, found := rt.find(total)
+	result := RollResult{
+		Table:  rt.Name,
+		Dice:   dice,
+		Total:  total,
+		Row:    row,
+		Missed: !found,
+		Result: row.Entry,
 	}
-	return rt.table[index]
+	if row.Subtable != nil {
+		subResult := row.Subtable.Explain(source)
+		result.Nested = append(result.Nested, subResult)
+		result.Result = strings.TrimRight(row.Entry, " ") + " " + subResult.Result
+	}
+	return result
+}
+
+// ExplainTimes rolls on the table count times, the same entry can be picked more than once
+func (rt RollableTable) ExplainTimes(count int, source RandomSource) []RollResult {
+	var results []RollResult
+	for i := 0; i < count; i++ {
+		results = append(results, rt.Explain(source))
+	}
+	return results
+}
+
+// ExplainDistinct picks count different entries from the table, like drawing from a deck without putting cards
+// back. Each pick is drawn from the totals of the dice that land on an entry not picked yet, as likely as they are
+// to be rolled, so no roll is wasted on an entry already picked. No dice are rolled, so the results have no Dice.
+func (rt RollableTable) ExplainDistinct(count int, source RandomSource) ([]RollResult, error) {
+	entries := map[string]bool{}
+	for _, chance := range rt.Chances() {
+		entries[chance.Row.Entry] = true
+	}
+	if count > len(entries) {
+		return nil, fmt.Errorf("%d different entries can't be picked from a table with %d", count, len(entries))
+	}
+	// each total is weighed by how many times as likely it is as the least likely one, which is exact for most
+	// dice, but no finer than a billionth for the long tails of exploding dice
+	distribution := rt.dice.Distribution()
+	smallest := 1.0
+	for _, p := range distribution {
+		if p >= negligible && p < smallest {
+			smallest = p
+		}
+	}
+	if smallest < 1./(1<<30) {
+		smallest = 1. / (1 << 30)
+	}
+	picked := map[string]bool{}
+	var results []RollResult
+	for len(results) < count {
+		var totals []int
+		for _, total := range distribution.Results() {
+			if row, found := rt.find(total); found && !picked[row.Entry] && distribution[total] >= negligible {
+				totals = append(totals, total)
+			}
+		}
+		if len(totals) == 0 {
+			return nil, fmt.Errorf("%d different entries can't be picked from a table with %d", count, len(results))
+		}
+		weights, sum := make([]int, len(totals)), 0
+		for i, total := range totals {
+			weights[i] = int(distribution[total]/smallest + .5)
+			if weights[i] < 1 {
+				weights[i] = 1
+			}
+			sum += weights[i]
+		}
+		draw, i := source.Intn(sum), 0
+		for ; draw >= weights[i]; i++ {
+			draw -= weights[i]
+		}
+		result := rt.explainTotal(totals[i], nil, source)
+		picked[result.Row.Entry] = true
+		results = append(results, result)
+	}
+	return results, nil
 }
 
 func (rt RollableTable) AsMDTable() string {
 	var table bytes.Buffer
-	for k, v := range rt.table {
-		table.WriteString(fmt.Sprintf("| %d | %s |\n", k, v))
+	for _, row := range rt.rows {
+		table.WriteString(fmt.Sprintf("| %s | %s |\n", row.Range(), row.Entry))
 	}
 	return table.String()
 }
 
+// ParseRollableTable parses the first list or table in a markdown document
 func ParseRollableTable(scanner bufio.Scanner, name string) (RollableTable, error) {
+	tables, err := ParseRollableTables(scanner, name)
+	if err != nil {
+		return RollableTable{}, err
+	}
+	table := tables[0]
+	table.Name = name
+	return table, nil
+}
+
+// ParseRollableTables parses every list or table in a markdown document, one per heading. When there is more
+// than one, each table under a heading is named after it, ie. 'Dungeon.md#Traps'.
+// Frontmatter at the top of the document applies to every table in it, though its dice are only rolled on the
+// tables they fit when there is more than one.
+func ParseRollableTables(scanner bufio.Scanner, name string) ([]RollableTable, error) {
 	var doc []string
-	for i := 0; i < 5; i++ { // Only check first couple lines before moving on
-		scanner.Scan()
+	for scanner.Scan() {
 		doc = append(doc, scanner.Text())
-		switch {
-		case isRollableMDList(scanner.Text()):
-			for scanner.Scan() {
-				doc = append(doc, scanner.Text())
+	}
+	frontmatter, rest := splitFrontmatter(doc)
+	metadata, err := parseFrontmatter(frontmatter, name, 1)
+	if err != nil {
+		return nil, err
+	}
+	offset := len(doc) - len(rest) // lines taken up by the frontmatter
+
+	var tables []RollableTable
+	var sectionErrs []*ParseError
+	for _, section := range splitMDSections(rest) {
+		table, ok, sectionErr := parseMDSection(section.lines, name, metadata.Weights, offset+section.start+1)
+		if sectionErr != nil {
+			sectionErrs = append(sectionErrs, sectionErr)
+		}
+		if !ok {
+			continue
+		}
+		table.heading = section.heading
+		tables = append(tables, table)
+	}
+	for i := range tables {
+		tables[i].applyMetadata(metadata, len(tables) == 1, frontmatterLine(frontmatter, "dice", 1))
+	}
+	if len(tables) == 0 {
+		if len(sectionErrs) > 0 {
+			return nil, sectionErrs[0]
+		}
+		return nil, &ParseError{File: name, Reason: "no list or table found",
+			Hint: "start each entry of a list with '* ' or '1. ', or give a markdown table a roll column"}
+	}
+	tables[0].warnings = append(tables[0].warnings, sectionErrs...) // sections that couldn't be read are left out
+	if len(tables) > 1 {
+		for i := range tables {
+			if tables[i].heading != "" {
+				tables[i].Name = name + "#" + tables[i].heading
 			}
-			return fromMDList(parseMDList(doc), name), nil
-		case isRollableMDTable(scanner.Text()):
-			for scanner.Scan() {
-				doc = append(doc, scanner.Text())
+		}
+	}
+	return tables, nil
+}
+
+// Select picks one of the tables parsed from a file by the part of a link after the '#'. That is the heading
+// the table is under, a column of the file's first table, or both like 'Encounters#Reaction'. Without a
+// fragment it picks the first table.
+func Select(tables []RollableTable, fragment string) (RollableTable, error) {
+	if len(tables) == 0 {
+		return RollableTable{}, fmt.Errorf("Not a Rollable Table")
+	}
+	if fragment == "" {
+		return tables[0], nil
+	}
+	heading, column, _ := strings.Cut(fragment, "#")
+	for _, table := range tables {
+		if table.heading != "" && strings.EqualFold(table.heading, strings.TrimSpace(heading)) {
+			if column == "" {
+				return table, nil
 			}
-			return fromMDTable(parseMDTable(doc), name)
+			return table.Column(column)
 		}
 	}
-	return RollableTable{}, fmt.Errorf("Not a Rollable Table")
+	if table, err := tables[0].Column(fragment); err == nil {
+		return table, nil
+	}
+	return RollableTable{}, fmt.Errorf("Table not found: %s has no heading or column %s", tables[0].Name, fragment)
+}
+
+type mdSection struct {
+	heading string
+	lines   []string
+	start   int // index in the document of the section's first line
 }
 
-func fromMDList(list MDList, name string) RollableTable {
+// splitMDSections splits a markdown document at each of its headings
+func splitMDSections(doc []string) []mdSection {
+	sections := []mdSection{{}}
+	for i, line := range doc {
+		if heading := markdownHeading.FindStringSubmatch(line); heading != nil {
+			sections = append(sections, mdSection{heading: heading[1], start: i + 1})
+			continue
+		}
+		sections[len(sections)-1].lines = append(sections[len(sections)-1].lines, line)
+	}
+	return sections
+}
+
+// parseMDSection parses the list or table that starts a section, skipping any text before it. weights are
+// how likely each entry of a list is to be picked, for entries that don't say themselves, and firstLine is
+// the line of the file the section starts on.
+func parseMDSection(lines []string, name string, weights map[string]int, firstLine int) (table RollableTable, ok bool, err *ParseError) {
+	for i, line := range lines {
+		switch {
+		case isRollableMDList(line):
+			return fromMDList(parseMDList(lines[i:]), name, weights), true, nil
+		case isRollableMDTable(line):
+			mdTable, positions := parseMDTableAt(lines[i:], firstLine+i)
+			table, err := fromMDTable(mdTable, name, positions)
+			if err != nil {
+				return table, false, err.(*ParseError)
+			}
+			return table, true, nil
+		}
+	}
+	return RollableTable{}, false, nil
+}
+
+// fromMDList makes a table rolling one die with a side for each entry of the list, or as many sides as an
+// entry's weight. The weight is given after the entry, ie. 'Goblins (x3)' or 'Goblins {w=3}', or in weights.
+func fromMDList(list MDList, name string, weights map[string]int) RollableTable {
 	var rollableTable RollableTable
 	rollableTable.Name = name
-	rollableTable.table = make(map[int]string)
-	rollableTable.max = len(list)
+	for _, line := range list {
+		entry, subList := splitSubList(line)
+		entry, weight := listEntryWeight(entry, weights)
+		row := Row{Min: rollableTable.max + 1, Max: rollableTable.max + weight, Entry: entry}
+		if len(subList) > 0 {
+			subtable := fromMDList(subList, name+"#"+strings.TrimSpace(entry), weights)
+			row.Subtable = &subtable
+		}
+		rollableTable.rows = append(rollableTable.rows, row)
+		rollableTable.max += weight
+	}
 	rollableTable.dice = Dice{
 		count:           1,
-		sides:           len(list),
+		sides:           rollableTable.max,
 		DiceInterpreter: AdditionInterpreter{},
 	}
-	for i, line := range list {
-		rollableTable.table[i+1] = line
-	}
 
 	return rollableTable
 }
 
-func fromMDTable(table MDTable, name string) (RollableTable, error) {
+// splitSubList separates a list entry from the list indented under it, ie. 'Sword' from the '* rusty' and
+// '* gleaming' below it
+func splitSubList(entry string) (string, MDList) {
+	lines := strings.Split(entry, "\n")
+	for i, line := range lines {
+		item := strings.TrimLeft(line, " \t")
+		if i == 0 || item == line || !isRollableMDList(item) {
+			continue
+		}
+		indent := line[:len(line)-len(item)]
+		for j := i; j < len(lines); j++ {
+			lines[j] = strings.TrimPrefix(lines[j], indent)
+		}
+		return strings.Join(lines[:i], "\n"), parseMDList(lines[i:])
+	}
+	return entry, nil
+}
+
+// listEntryWeight separates a list entry from its weight, which is 1 unless the entry or weights says otherwise
+func listEntryWeight(line string, weights map[string]int) (entry string, weight int) {
+	if match := listItemWeight.FindStringSubmatch(line); match != nil {
+		weight, _ = strconv.Atoi(match[1] + match[2]) // only one of the two forms matched
+		return strings.TrimSuffix(line, match[0]), weight
+	}
+	for weighted, weight := range weights {
+		if strings.EqualFold(strings.TrimSpace(weighted), strings.TrimSpace(line)) && weight > 0 {
+			return line, weight
+		}
+	}
+	return line, 1
+}
+
+// fromMDTable makes a table from the rows of a markdown table, the first of which is its header. positions
+// locates each row's roll in the file it was read from, if known, for the warnings about rows that are skipped.
+func fromMDTable(table MDTable, name string, positions []position) (RollableTable, error) {
 	var rollableTable RollableTable
 	rollableTable.Name = name
-	rollableTable.table = make(map[int]string)
-	for _, row := range table {
-		minRange, maxRange, value, ok := parseMDTableRow(row)
-		if ok {
-			rollableTable.table[minRange] = value
-			for i := minRange + 1; i <= maxRange; i++ {
-				rollableTable.table[i] = strconv.Itoa(minRange)
+	if len(table) > 0 && len(table[0]) > 2 {
+		for _, column := range table[0][1:] {
+			rollableTable.columns = append(rollableTable.columns, strings.TrimSpace(column))
+		}
+	}
+	for i, cells := range table {
+		// the padding around a cell is only there to line up the markdown, so it isn't part of the entry
+		row := make([]string, len(cells))
+		for j, cell := range cells {
+			row[j] = strings.TrimSpace(cell)
+		}
+		rolls, value, ok := parseMDTableRow(row)
+		if !ok && i > 0 && !markdownTableSeparator.MatchString(row[0]) {
+			var at position
+			if i < len(positions) {
+				at = positions[i]
+			}
+			rollableTable.warn(at.line, at.column, "write the roll like '3', '3-7', '19+' or '≤2'",
+				"row skipped, '%s' isn't a roll", strings.TrimSpace(row[0]))
+		}
+		for _, roll := range rolls {
+			tableRow := Row{Min: roll.Min, Max: roll.Max, Entry: value}
+			if len(rolls) > 1 {
+				tableRow.group = i + 1
+			}
+			if rollableTable.columns != nil {
+				tableRow.Fields = row[1:]
+				tableRow.Entry = namedFields(rollableTable.columns, tableRow.Fields)
 			}
-			if rollableTable.max < maxRange {
-				rollableTable.max = maxRange
+			rollableTable.rows = append(rollableTable.rows, tableRow)
+			highest := roll.Max
+			if roll.Max == math.MaxInt {
+				highest = roll.Min
+			}
+			if rollableTable.max < highest {
+				rollableTable.max = highest
 			}
 		}
 	}
-	if rollableTable.max == 0 || len(rollableTable.table) == 0 {
-		return rollableTable, fmt.Errorf("Table not parsable as Rollable Table, table max: %d, table length: %d", rollableTable.max, len(rollableTable.table))
+	if rollableTable.max == 0 || len(rollableTable.rows) == 0 {
+		var at position
+		if len(positions) > 0 {
+			at = positions[0]
+		}
+		return rollableTable, &ParseError{File: name, Line: at.line, Reason: "table has no rows with a roll",
+			Hint: "the first column of each row should be a roll like '3', '3-7' or '19+'"}
 	}
 	die, dieDefined := parseDiceFromString(table[0][0])
 	if !dieDefined {
@@ -105,38 +474,88 @@ func fromMDTable(table MDTable, name string) (RollableTable, error) {
 		}
 	}
 	rollableTable.dice = die
+	rollableTable.headerDice = dieDefined
 	return rollableTable, nil
 }
 
-func parseMDTableRow(row []string) (min int, max int, value string, ok bool) {
-	rowRange := rowRangePattern.FindAllStringSubmatch(row[0], -1)
+// namedFields writes out the fields of a row of a multi-column table, ie. 'Encounter: Goblins, Reaction: Hostile'
+func namedFields(columns []string, fields []string) string {
+	var named []string
+	for i, field := range fields {
+		field = strings.TrimSpace(field)
+		switch {
+		case field == "":
+		case i >= len(columns) || columns[i] == "":
+			named = append(named, field)
+		default:
+			named = append(named, columns[i]+": "+field)
+		}
+	}
+	return strings.Join(named, ", ")
+}
+
+// parseMDTableRow reads the roll in the first cell of a row, which can be a list like '1, 3, 5' or '5 or 6' that
+// picks the row for more than one range
+func parseMDTableRow(row []string) (rolls []Row, value string, ok bool) {
+	// the roll can have emphasis or a note after it, ie. '**1-2**' or '1-2 (rare)', but not run into a word like
+	// '2d6' or be followed by more numbers like '5 to 6'
+	cell := markdownEmphasis.ReplaceAllString(strings.TrimSpace(row[0]), "")
+	roll := rollCellPrefix.FindString(cell)
+	rest := cell[len(roll):]
+	first, _ := utf8.DecodeRuneInString(rest)
+	if strings.ContainsAny(rest, "0123456789") || unicode.IsLetter(first) && strings.TrimSpace(roll) == roll {
+		return nil, "", false
+	}
+	for _, part := range rollListSeparator.Split(strings.TrimSpace(roll), -1) {
+		roll, ok := parseRoll(part)
+		if !ok {
+			return nil, "", false
+		}
+		rolls = append(rolls, roll)
+	}
+	return rolls, row[1], true
+}
 
-	if len(rowRange) == 1 && len(rowRange[0]) == 3 {
-		min, err := strconv.Atoi(rowRange[0][1])
+// parseRoll reads a single range like '3', '3-7', '96–00', '19+' or '≤2'
+func parseRoll(roll string) (Row, bool) {
+	if rowRange := rowRangePattern.FindStringSubmatch(roll); rowRange != nil {
+		min, err := rollNumber(rowRange[1])
 		if err != nil {
-			fmt.Printf("Error parsing min range, %v, %s\n", row, err)
-			return 0, 0, "", false
+			return Row{}, false
 		}
-		max, err = strconv.Atoi(rowRange[0][2])
+		max, err := rollNumber(rowRange[2])
 		if err != nil {
-			fmt.Printf("Error parsing max range, %v, %s\n", row, err)
-			return 0, 0, "", false
+			return Row{}, false
 		}
-		return min, max, row[1], true
+		return Row{Min: min, Max: max}, true
 	}
-	num, err := strconv.Atoi(strings.TrimSpace(row[0]))
-	if err == nil {
-		return num, num, row[1], true
+	if openRange := openRangePattern.FindStringSubmatch(roll); openRange != nil {
+		if openRange[1] != "" {
+			min, err := rollNumber(openRange[1])
+			return Row{Min: min, Max: math.MaxInt}, err == nil
+		}
+		max, err := rollNumber(openRange[2])
+		return Row{Min: math.MinInt, Max: max}, err == nil
 	}
-	return 0, 0, "", false
+	num, err := rollNumber(roll)
+	return Row{Min: num, Max: num}, err == nil
+}
+
+// rollNumber reads a number in a roll. Like the dice it is read from, a number of only zeros is the highest
+// result rather than 0, ie. '00' is 100 on a d100.
+func rollNumber(s string) (int, error) {
+	if len(s) > 1 && strings.Trim(s, "0") == "" {
+		return int(math.Pow10(len(s))), nil
+	}
+	return strconv.Atoi(s)
 }
 
 type MDTable [][]string
 
 func isRollableMDTable(s string) bool {
 	if strings.HasPrefix(s, "|") {
-		columns := strings.Count(s, "|") - strings.Count(s, `\|`)
-		if columns == 3 {
+		pipes := strings.Count(s, "|") - strings.Count(s, `\|`)
+		if pipes >= 3 { // a roll column and at least one result column
 			return true
 		}
 	}
@@ -144,13 +563,52 @@ func isRollableMDTable(s string) bool {
 }
 
 func parseMDTable(contents []string) MDTable {
+	mdTable, _ := parseMDTableAt(contents, 1)
+	return mdTable
+}
+
+// position is where something is in a file, counting lines and columns from 1
+type position struct {
+	line, column int
+}
+
+// parseMDTableAt parses a markdown table that starts on firstLine of a file, along with the position of the
+// roll of each of its rows
+func parseMDTableAt(contents []string, firstLine int) (MDTable, []position) {
 	var mdTable MDTable
-	for _, line := range contents {
-		if strings.HasPrefix(line, "|") && strings.HasSuffix(line, "|") {
-			mdTable = append(mdTable, strings.Split(line, "|")[1:3])
+	var positions []position
+	for i, line := range contents {
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "|") && strings.HasSuffix(line, "|") && len(line) > 1 {
+			cells := splitMDTableRow(line)
+			if len(cells) >= 2 {
+				mdTable = append(mdTable, cells)
+				roll := len(cells[0]) - len(strings.TrimLeft(cells[0], " "))
+				positions = append(positions, position{line: firstLine + i, column: indent + roll + 2})
+			}
 		}
 	}
-	return mdTable
+	return mdTable, positions
+}
+
+// splitMDTableRow splits a row like '| 1 | a \| b |' into its cells, ' 1 ' and ' a | b '
+func splitMDTableRow(line string) []string {
+	var cells []string
+	cell := strings.Builder{}
+	for i := 1; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, cell.String())
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return cells
 }
 
 type MDList []string
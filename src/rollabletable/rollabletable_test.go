@@ -3,6 +3,7 @@ package rollabletable
 import (
 	"bufio"
 	"fmt"
+	"math"
 	"regexp"
 	"strings"
 	"testing"
@@ -55,7 +56,8 @@ func Test_isRollableMDTable(t *testing.T) {
 	assert.True(t, isRollableMDTable("| 1-2 | bar |"))
 	assert.True(t, isRollableMDTable("|3-5|bar|"))
 	assert.True(t, isRollableMDTable("|---|---|"))
-	assert.False(t, isRollableMDTable("| foo | bar | baz |"))
+	assert.True(t, isRollableMDTable("| foo | bar | baz |"))
+	assert.False(t, isRollableMDTable("| foo |"))
 }
 
 func Test_parseMDTable(t *testing.T) {
@@ -66,92 +68,187 @@ func Test_parseMDTable(t *testing.T) {
 
 func Test_parseMDTableRow(t *testing.T) {
 	row := [][]string{{" 7-20 ", " foo "}, {" 10 ", " bar "}, {" badinput ", " baz "}}
-	min, max, value, ok := parseMDTableRow(row[0])
+	rolls, value, ok := parseMDTableRow(row[0])
 	assert.True(t, ok)
-	assert.Equal(t, 7, min)
-	assert.Equal(t, 20, max)
+	assert.Equal(t, []Row{{Min: 7, Max: 20}}, rolls)
 	assert.Equal(t, " foo ", value)
 
-	min, max, value, ok = parseMDTableRow(row[1])
+	rolls, value, ok = parseMDTableRow(row[1])
 	assert.True(t, ok)
-	assert.Equal(t, 10, min)
-	assert.Equal(t, 10, max)
+	assert.Equal(t, []Row{{Min: 10, Max: 10}}, rolls)
 	assert.Equal(t, " bar ", value)
 
-	min, max, value, ok = parseMDTableRow(row[2])
+	_, _, ok = parseMDTableRow(row[2])
 	assert.False(t, ok)
 }
 
+func Test_parseMDTableRow_notation(t *testing.T) {
+	for roll, expected := range map[string][]Row{
+		"11–00":      {{Min: 11, Max: 100}},
+		"96 – 00":    {{Min: 96, Max: 100}},
+		"00":         {{Min: 100, Max: 100}},
+		"000":        {{Min: 1000, Max: 1000}},
+		"0":          {{Min: 0, Max: 0}},
+		"01-05":      {{Min: 1, Max: 5}},
+		"19+":        {{Min: 19, Max: math.MaxInt}},
+		"5 or 6":     {{Min: 5, Max: 5}, {Min: 6, Max: 6}},
+		"1, 3, 5":    {{Min: 1, Max: 1}, {Min: 3, Max: 3}, {Min: 5, Max: 5}},
+		"1,3-4":      {{Min: 1, Max: 1}, {Min: 3, Max: 4}},
+		"2 or 11+":   {{Min: 2, Max: 2}, {Min: 11, Max: math.MaxInt}},
+		"**1-2**":    {{Min: 1, Max: 2}},
+		"_3_":        {{Min: 3, Max: 3}},
+		"4-5 (rare)": {{Min: 4, Max: 5}},
+	} {
+		rolls, _, ok := parseMDTableRow([]string{" " + roll + " ", " foo "})
+		assert.True(t, ok, roll)
+		assert.Equal(t, expected, rolls, roll)
+	}
+	for _, roll := range []string{"2d6-2", "1d20", "1,", "or 5", "5 to 6", "1-2-3", "1st", "**d6**"} {
+		_, _, ok := parseMDTableRow([]string{roll, " foo "})
+		assert.False(t, ok, roll)
+	}
+}
+
+func Test_ParseRollableTable_emphasisedRolls(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("| **d4** | result |\n|---|---|\n| **1-2** | a |\n| *3-4* (rare) | b |\n")), "emphasis")
+	assert.NoError(t, err)
+	assert.Empty(t, table.Warnings())
+	assert.Equal(t, []Row{{Min: 1, Max: 2, Entry: "a"}, {Min: 3, Max: 4, Entry: "b"}}, table.Rows())
+}
+
+func TestRollableTable_d100(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("| d% | Loot |\n|---|---|\n| 01–95 | copper |\n| 96–00 | gold |\n")), "loot")
+	assert.NoError(t, err)
+	assert.Equal(t, "gold", table.RollWith(NewScriptedSource(9, 9)))   // 00
+	assert.Equal(t, "gold", table.RollWith(NewScriptedSource(8, 5)))   // 96
+	assert.Equal(t, "copper", table.RollWith(NewScriptedSource(8, 4))) // 95
+	assert.Empty(t, Validate(table, nil))
+}
+
+func TestRollableTable_rollList(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("| d6 | Weather |\n|---|---|\n| 1, 3, 5 | rain |\n| 2 or 4 | sun |\n| 6 | snow |\n")), "weather")
+	assert.NoError(t, err)
+	assert.Equal(t, "rain", table.RollWith(NewScriptedSource(4)))
+	assert.Equal(t, "sun", table.RollWith(NewScriptedSource(3)))
+	assert.Empty(t, Validate(table, nil))
+	assert.InDelta(t, 0.5, table.Chances()[0].Chance, 1e-9)
+}
+
 func Test_fromMDTable(t *testing.T) {
 	table := MDTable{{" 1-3 ", " A "}, {" 4-6 ", " B "}}
-	rollableTable, err := fromMDTable(table, "normaltable")
+	rollableTable, err := fromMDTable(table, "normaltable", nil)
 	assert.NoError(t, err)
-	assert.Equal(t, map[int]string{1: " A ", 2: "1", 3: "1", 4: " B ", 5: "4", 6: "4"}, rollableTable.table)
+	assert.Equal(t, []Row{{Min: 1, Max: 3, Entry: "A"}, {Min: 4, Max: 6, Entry: "B"}}, rollableTable.rows)
 	assert.Equal(t, 6, rollableTable.max)
 }
 
 func Test_fromMDTable_badFormat(t *testing.T) {
 	table := MDTable{{" A ", " B "}, {" bar ", " baz "}}
-	_, err := fromMDTable(table, "badformat")
+	_, err := fromMDTable(table, "badformat", nil)
 	assert.Error(t, err)
 }
 
 func Test_fromMDList(t *testing.T) {
 	list := MDList{"foo", "bar", "baz"}
-	rollableTable := fromMDList(list, "normallist")
-	assert.Equal(t, map[int]string{1: "foo", 2: "bar", 3: "baz"}, rollableTable.table)
+	rollableTable := fromMDList(list, "normallist", nil)
+	assert.Equal(t, []Row{{Min: 1, Max: 1, Entry: "foo"}, {Min: 2, Max: 2, Entry: "bar"}, {Min: 3, Max: 3, Entry: "baz"}}, rollableTable.rows)
 	assert.Equal(t, 3, rollableTable.max)
 }
 
+func Test_fromMDList_weighted(t *testing.T) {
+	list := MDList{"Goblins (x3)", "Orcs {w=2}", "Dragon", "Ogre", "Troll (x)"}
+	rollableTable := fromMDList(list, "weighted", map[string]int{"ogre": 4, "Goblins": 10})
+	assert.Equal(t, []Row{
+		{Min: 1, Max: 3, Entry: "Goblins"},
+		{Min: 4, Max: 5, Entry: "Orcs"},
+		{Min: 6, Max: 6, Entry: "Dragon"},
+		{Min: 7, Max: 10, Entry: "Ogre"},
+		{Min: 11, Max: 11, Entry: "Troll (x)"},
+	}, rollableTable.rows)
+	assert.Equal(t, 11, rollableTable.max)
+	assert.Equal(t, "1d11", rollableTable.dice.String())
+	assert.InDelta(t, 3.0/11, rollableTable.Chances()[0].Chance, 1e-9)
+}
+
+func Test_ParseRollableTable_subList(t *testing.T) {
+	doc := "* Sword (x2)\n  * rusty\n  * gleaming\n    * with runes\n    * with gems\n* Shield\n"
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader(doc)), "weapons")
+	assert.NoError(t, err)
+	assert.Len(t, table.rows, 2)
+	assert.Equal(t, "Sword", table.rows[0].Entry)
+	assert.Equal(t, 2, table.rows[0].Max)
+	assert.Nil(t, table.rows[1].Subtable)
+
+	sword := table.rows[0].Subtable
+	assert.Equal(t, "weapons#Sword", sword.Name)
+	assert.Equal(t, "rusty", sword.rows[0].Entry)
+	assert.Equal(t, "gleaming", sword.rows[1].Entry)
+	assert.Equal(t, "with gems", sword.rows[1].Subtable.rows[1].Entry)
+
+	result := table.Explain(NewScriptedSource(0, 1, 1))
+	assert.Equal(t, "Sword gleaming with gems", result.Result)
+	assert.Equal(t, "Sword", result.Row.Entry)
+	assert.Len(t, result.Nested, 1)
+	assert.Equal(t, "gleaming with gems", result.Nested[0].Result)
+	assert.Equal(t, "Shield", table.RollWith(NewScriptedSource(2)))
+}
+
 func Test_ParseRollableTable_list(t *testing.T) {
 	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("* foo\n* bar\n* baz\n")), "mdtable")
 	assert.NoError(t, err)
-	assert.Equal(t, map[int]string{1: "foo", 2: "bar", 3: "baz"}, table.table)
+	assert.Equal(t, []Row{{Min: 1, Max: 1, Entry: "foo"}, {Min: 2, Max: 2, Entry: "bar"}, {Min: 3, Max: 3, Entry: "baz"}}, table.rows)
 	assert.Equal(t, 3, table.max)
 }
 
 func Test_ParseRollableTable_table_withRanges(t *testing.T) {
 	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("| foo | bar |\n|---|---|\n| 1-3 | A |\n| 4-6 | B |")), "mdtable")
 	assert.NoError(t, err)
-	assert.Equal(t, map[int]string{1: " A ", 2: "1", 3: "1", 4: " B ", 5: "4", 6: "4"}, table.table)
+	assert.Equal(t, []Row{{Min: 1, Max: 3, Entry: "A"}, {Min: 4, Max: 6, Entry: "B"}}, table.rows)
 	assert.Equal(t, 6, table.max)
 }
 
 func Test_ParseRollableTable_table_withoutRanges(t *testing.T) {
 	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("| foo | bar |\n|---|---|\n| 1 | A |\n| 2 | B |\n| 3 | C |")), "noranges")
 	assert.NoError(t, err)
-	assert.Equal(t, map[int]string{1: " A ", 2: " B ", 3: " C "}, table.table)
+	assert.Equal(t, []Row{{Min: 1, Max: 1, Entry: "A"}, {Min: 2, Max: 2, Entry: "B"}, {Min: 3, Max: 3, Entry: "C"}}, table.rows)
 	assert.Equal(t, 3, table.max)
 }
 
 func Test_ParseRollableTable_complexTable(t *testing.T) {
 	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("| 2d6 | options |\n|---|---|\n| 2 | foo |\n| 3-7 | bar |\n| 8 | baz |\n| 9-12 | bing |")), "complextable")
 	assert.NoError(t, err)
-	assert.Equal(t, map[int]string{2: " foo ", 3: " bar ", 4: "3", 5: "3", 6: "3", 7: "3", 8: " baz ", 9: " bing ", 10: "9", 11: "9", 12: "9"}, table.table)
+	assert.Equal(t, []Row{{Min: 2, Max: 2, Entry: "foo"}, {Min: 3, Max: 7, Entry: "bar"}, {Min: 8, Max: 8, Entry: "baz"}, {Min: 9, Max: 12, Entry: "bing"}}, table.rows)
 	assert.Equal(t, 12, table.max)
-	assert.Equal(t, 2, table.dice.count)
-	assert.Equal(t, 6, table.dice.sides)
+	assert.Equal(t, Dice{count: 2, sides: 6, DiceInterpreter: AdditionInterpreter{}}, table.dice)
 }
 
 func Test_parseDiceFromMDTable(t *testing.T) {
 	table := MDTable{{" 2d20 ", " result "}, {"---", "---"}, {" 1-3 ", " A "}, {" 4-6 ", " B "}, {" 7-20 ", " C "}}
-	rollableTable, err := fromMDTable(table, "2d20table")
+	rollableTable, err := fromMDTable(table, "2d20table", nil)
 	assert.Nil(t, err)
 	assert.NotNil(t, rollableTable.dice)
-	assert.Equal(t, 2, rollableTable.dice.count)
-	assert.Equal(t, 20, rollableTable.dice.sides)
+	assert.Equal(t, Dice{count: 2, sides: 20, DiceInterpreter: AdditionInterpreter{}}, rollableTable.dice)
 	assert.Equal(t, 20, rollableTable.max)
 }
 
+func Test_parseDiceExpressionFromMDTable(t *testing.T) {
+	table := MDTable{{" 2d6+1 ", " result "}, {"---", "---"}, {" 3-8 ", " A "}, {" 9-13 ", " B "}}
+	rollableTable, err := fromMDTable(table, "modifiedtable", nil)
+	assert.NoError(t, err)
+	for i := 0; i < 50; i++ {
+		assert.Contains(t, []string{"A", "B"}, rollableTable.Roll())
+	}
+}
+
 func Test_Roll(t *testing.T) {
-	table := RollableTable{"RollIt", map[int]string{1: "foo", 2: "bar", 3: "baz"}, 3, Dice{1, 3, AdditionInterpreter{}}}
+	table := RollableTable{Name: "RollIt", rows: []Row{{Min: 1, Max: 1, Entry: "foo"}, {Min: 2, Max: 2, Entry: "bar"}, {Min: 3, Max: 3, Entry: "baz"}}, max: 3, dice: Dice{count: 1, sides: 3, DiceInterpreter: AdditionInterpreter{}}}
 	match, err := regexp.Match(`foo|bar|baz`, []byte(table.Roll()))
 	assert.NoError(t, err)
 	assert.True(t, match)
 }
 
 func Test_Roll_WithDice1d3(t *testing.T) {
-	table := RollableTable{"Roll1d3", map[int]string{1: "foo", 2: "bar", 3: "baz", 4: "bing", 5: "bong"}, 5, Dice{
+	table := RollableTable{Name: "Roll1d3", rows: []Row{{Min: 1, Max: 1, Entry: "foo"}, {Min: 2, Max: 2, Entry: "bar"}, {Min: 3, Max: 3, Entry: "baz"}, {Min: 4, Max: 4, Entry: "bing"}, {Min: 5, Max: 5, Entry: "bong"}}, max: 5, dice: Dice{
 		count:           1,
 		sides:           3,
 		DiceInterpreter: AdditionInterpreter{},
@@ -162,7 +259,7 @@ func Test_Roll_WithDice1d3(t *testing.T) {
 }
 
 func Test_Roll_WithDice2d2(t *testing.T) {
-	table := RollableTable{"2d2table", map[int]string{1: "foo", 2: "bar", 3: "baz", 4: "bing", 5: "bong"}, 3, Dice{
+	table := RollableTable{Name: "2d2table", rows: []Row{{Min: 1, Max: 1, Entry: "foo"}, {Min: 2, Max: 2, Entry: "bar"}, {Min: 3, Max: 3, Entry: "baz"}, {Min: 4, Max: 4, Entry: "bing"}, {Min: 5, Max: 5, Entry: "bong"}}, max: 3, dice: Dice{
 		count:           2,
 		sides:           2,
 		DiceInterpreter: AdditionInterpreter{},
@@ -171,3 +268,250 @@ func Test_Roll_WithDice2d2(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, match)
 }
+
+func Test_ParseRollableTable_successPool(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("| 3d1>=1 | successes |\n|---|---|\n| 0-2 | few |\n| 3 | all |")), "successes")
+	assert.NoError(t, err)
+	assert.Equal(t, "all", table.Roll())
+}
+
+func Test_ParseRollableTable_fudgeDice(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("| 4dF | result |\n|---|---|\n| -4--1 | bad |\n| 0 | even |\n| 1-4 | good |")), "fudge")
+	assert.NoError(t, err)
+	for i := 0; i < 50; i++ {
+		assert.Contains(t, []string{"bad", "even", "good"}, table.Roll())
+	}
+}
+
+func TestRollableTable_Explain(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("| 2d6 | options |\n|---|---|\n| 2 | foo |\n| 3-7 | bar |\n| 8-12 | baz |")), "explained")
+	assert.NoError(t, err)
+	result := table.Explain(NewScriptedSource(1, 2))
+	assert.Equal(t, RollResult{
+		Table:  "explained",
+		Dice:   []DiceRoll{{Dice: "2d6", Result: DieResult{2, 3}, Total: 5}},
+		Total:  5,
+		Row:    Row{Min: 3, Max: 7, Entry: "bar"},
+		Result: "bar",
+	}, result)
+
+	result = table.Explain(NewScriptedSource(0, 0))
+	assert.Equal(t, Row{Min: 2, Max: 2, Entry: "foo"}, result.Row)
+}
+
+func Test_parseMDTableRow_openEnded(t *testing.T) {
+	rolls, _, ok := parseMDTableRow([]string{" 19+ ", " foo "})
+	assert.True(t, ok)
+	assert.Equal(t, []Row{{Min: 19, Max: math.MaxInt}}, rolls)
+
+	rolls, _, ok = parseMDTableRow([]string{" ≤2 ", " foo "})
+	assert.True(t, ok)
+	assert.Equal(t, []Row{{Min: math.MinInt, Max: 2}}, rolls)
+
+	rolls, _, ok = parseMDTableRow([]string{" <= -1 ", " foo "})
+	assert.True(t, ok)
+	assert.Equal(t, []Row{{Min: math.MinInt, Max: -1}}, rolls)
+}
+
+func TestRollableTable_numericEntries(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("| d4 | gold |\n|---|---|\n| 1-2 | 7 |\n| 3 | 100 |\n| 4 | 3 |")), "numbers")
+	assert.NoError(t, err)
+	assert.Equal(t, "7", strings.TrimSpace(table.RollWith(NewScriptedSource(0))))
+	assert.Equal(t, "7", strings.TrimSpace(table.RollWith(NewScriptedSource(1))))
+	assert.Equal(t, "100", strings.TrimSpace(table.RollWith(NewScriptedSource(2))))
+	assert.Equal(t, "3", strings.TrimSpace(table.RollWith(NewScriptedSource(3))))
+}
+
+func TestRollableTable_openEnded(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("| 1d20+1d4 | result |\n|---|---|\n| ≤5 | low |\n| 6-18 | mid |\n| 19+ | high |")), "open")
+	assert.NoError(t, err)
+	assert.Equal(t, 19, table.max)
+	assert.Equal(t, "low", table.RollWith(NewScriptedSource(0, 0)))
+	assert.Equal(t, "high", table.RollWith(NewScriptedSource(19, 3)))
+	assert.Equal(t, []Row{{Min: math.MinInt, Max: 5, Entry: "low"}, {Min: 6, Max: 18, Entry: "mid"}, {Min: 19, Max: math.MaxInt, Entry: "high"}}, table.Rows())
+
+	chances := table.Chances()
+	total := 0.0
+	for _, chance := range chances {
+		total += chance.Chance
+	}
+	assert.InDelta(t, 1, total, 1e-9)
+}
+
+func TestRow_Range(t *testing.T) {
+	assert.Equal(t, "3", Row{Min: 3, Max: 3}.Range())
+	assert.Equal(t, "3-7", Row{Min: 3, Max: 7}.Range())
+	assert.Equal(t, "19+", Row{Min: 19, Max: math.MaxInt}.Range())
+	assert.Equal(t, "≤2", Row{Min: math.MinInt, Max: 2}.Range())
+}
+
+func Test_parseMDTable_multiColumn(t *testing.T) {
+	s := []string{"| d20 | Encounter | Reaction |", "|---|---|---|", "| 1-10 | Goblins | Hostile \\| Wary |  "}
+	assert.Equal(t, MDTable{{" d20 ", " Encounter ", " Reaction "}, {"---", "---", "---"}, {" 1-10 ", " Goblins ", " Hostile | Wary "}}, parseMDTable(s))
+}
+
+func TestRollableTable_multiColumn(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader(
+		"| d2 | Encounter | Number Appearing | Reaction |\n|---|---|---|---|\n| 1 | Goblins | 2d4 | Hostile |\n| 2 | Wolves | | Hungry |")), "Encounters")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Encounter", "Number Appearing", "Reaction"}, table.Columns())
+	assert.Equal(t, "Encounter: Goblins, Number Appearing: 2d4, Reaction: Hostile", table.RollWith(NewScriptedSource(0)))
+	assert.Equal(t, "Encounter: Wolves, Reaction: Hungry", table.RollWith(NewScriptedSource(1)))
+
+	row := table.Explain(NewScriptedSource(0)).Row
+	assert.Equal(t, []string{"Goblins", "2d4", "Hostile"}, row.Fields)
+	reaction, ok := table.Field(row, "reaction")
+	assert.True(t, ok)
+	assert.Equal(t, "Hostile", reaction)
+	_, ok = table.Field(row, "Treasure")
+	assert.False(t, ok)
+
+	reactions, err := table.Column("Reaction")
+	assert.NoError(t, err)
+	assert.Equal(t, "Encounters#Reaction", reactions.Name)
+	assert.Equal(t, "Hungry", reactions.RollWith(NewScriptedSource(1)))
+	assert.Empty(t, reactions.Columns())
+
+	_, err = table.Column("Treasure")
+	assert.Error(t, err)
+}
+
+func TestRollableTable_Explain_missed(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("| d4 | result |\n|---|---|\n| 1-3 | foo |")), "gap")
+	assert.NoError(t, err)
+	assert.True(t, table.Explain(NewScriptedSource(3)).Missed)
+	assert.False(t, table.Explain(NewScriptedSource(2)).Missed)
+}
+
+func Test_ParseRollableTable_skipsTextBeforeList(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("# Header\nSome notes\n\n* foo\n* bar\n")), "header")
+	assert.NoError(t, err)
+	assert.Equal(t, "header", table.Name)
+	assert.Equal(t, []Row{{Min: 1, Max: 1, Entry: "foo"}, {Min: 2, Max: 2, Entry: "bar"}}, table.rows)
+}
+
+func Test_ParseRollableTable_farDownTheFile(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("a\nb\nc\nd\ne\nf\n| d2 | x |\n|---|---|\n| 1 | foo |\n| 2 | bar |")), "late")
+	assert.NoError(t, err)
+	assert.Len(t, table.rows, 2)
+}
+
+func Test_ParseRollableTable_notATable(t *testing.T) {
+	_, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("# Notes\nJust some text\n")), "notes")
+	assert.Error(t, err)
+}
+
+const dungeonDoc = `# Dungeon
+Roll on the tables below.
+
+## Traps
+* Pit
+* Darts
+
+## Monsters
+| d2 | Monster | Reaction |
+|----|---------|----------|
+| 1  | Goblin  | Hostile  |
+| 2  | Rat     | Hungry   |
+
+## Notes
+Nothing to roll here.
+`
+
+func Test_ParseRollableTables(t *testing.T) {
+	tables, err := ParseRollableTables(*bufio.NewScanner(strings.NewReader(dungeonDoc)), "Dungeon.md")
+	assert.NoError(t, err)
+	assert.Len(t, tables, 2)
+	assert.Equal(t, "Dungeon.md#Traps", tables[0].Name)
+	assert.Equal(t, "Dungeon.md#Monsters", tables[1].Name)
+	assert.Equal(t, "Darts", tables[0].RollWith(NewScriptedSource(1)))
+
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader(dungeonDoc)), "Dungeon.md")
+	assert.NoError(t, err)
+	assert.Equal(t, "Dungeon.md", table.Name)
+	assert.Equal(t, "Pit", table.RollWith(NewScriptedSource(0)))
+}
+
+func Test_ParseRollableTables_singleTableKeepsName(t *testing.T) {
+	tables, err := ParseRollableTables(*bufio.NewScanner(strings.NewReader("# Header\n* foo\n* bar\n")), "single.md")
+	assert.NoError(t, err)
+	assert.Len(t, tables, 1)
+	assert.Equal(t, "single.md", tables[0].Name)
+}
+
+func TestSelect(t *testing.T) {
+	tables, err := ParseRollableTables(*bufio.NewScanner(strings.NewReader(dungeonDoc)), "Dungeon.md")
+	assert.NoError(t, err)
+
+	table, err := Select(tables, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "Dungeon.md#Traps", table.Name)
+
+	table, err = Select(tables, "monsters")
+	assert.NoError(t, err)
+	assert.Equal(t, "Dungeon.md#Monsters", table.Name)
+
+	table, err = Select(tables, "Monsters#Reaction")
+	assert.NoError(t, err)
+	assert.Equal(t, "Dungeon.md#Monsters#Reaction", table.Name)
+	assert.Equal(t, "Hungry", strings.TrimSpace(table.RollWith(NewScriptedSource(1))))
+
+	_, err = Select(tables, "Notes")
+	assert.Error(t, err)
+	_, err = Select(nil, "")
+	assert.Error(t, err)
+}
+
+func Test_splitMDSections(t *testing.T) {
+	sections := splitMDSections([]string{"intro", "## First ##", "a", "#hashtag", "### Second", "b"})
+	assert.Equal(t, []mdSection{
+		{lines: []string{"intro"}},
+		{heading: "First", lines: []string{"a", "#hashtag"}, start: 2},
+		{heading: "Second", lines: []string{"b"}, start: 5},
+	}, sections)
+}
+
+func TestRollableTable_ExplainTimes(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("* foo\n* bar\n* baz\n")), "mdtable")
+	assert.NoError(t, err)
+	var entries []string
+	for _, result := range table.ExplainTimes(3, NewScriptedSource(1, 1, 0)) {
+		entries = append(entries, result.Result)
+	}
+	assert.Equal(t, []string{"bar", "bar", "foo"}, entries)
+	assert.Empty(t, table.ExplainTimes(0, NewScriptedSource(0)))
+}
+
+func TestRollableTable_ExplainDistinct(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("| d6 | Weather |\n|---|---|\n| 1, 3, 5 | rain |\n| 2 or 4 | sun |\n| 6 | snow |\n")), "weather")
+	assert.NoError(t, err)
+	// rain is picked from all six totals, then snow from the 2, 4 and 6 left
+	results, err := table.ExplainDistinct(2, NewScriptedSource(0, 2))
+	assert.NoError(t, err)
+	assert.Equal(t, "rain", results[0].Result)
+	assert.Equal(t, "snow", results[1].Result)
+	assert.Equal(t, 6, results[1].Total)
+	assert.Nil(t, results[1].Dice)
+
+	results, err = table.ExplainDistinct(3, NewScriptedSource(0))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rain", "sun", "snow"}, []string{results[0].Result, results[1].Result, results[2].Result})
+
+	_, err = table.ExplainDistinct(4, NewScriptedSource(0))
+	assert.EqualError(t, err, "4 different entries can't be picked from a table with 3")
+}
+
+func TestRollableTable_ExplainDistinct_weighted(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("| 2d6 | Weather |\n|---|---|\n| 2-6 | rain |\n| 7 | sun |\n| 8-11 | snow |\n")), "weather")
+	assert.NoError(t, err)
+	// 12 lands on no row, so of the 35 ways to roll a total that does, 15 are rain, 6 sun and 14 snow
+	results, err := table.ExplainDistinct(2, NewScriptedSource(14, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rain", "sun"}, []string{results[0].Result, results[1].Result})
+	assert.Equal(t, []int{6, 7}, []int{results[0].Total, results[1].Total})
+
+	results, err = table.ExplainDistinct(3, NewScriptedSource(15, 0, 13))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sun", "rain", "snow"}, []string{results[0].Result, results[1].Result, results[2].Result})
+	assert.Equal(t, 11, results[2].Total)
+}
@@ -0,0 +1,118 @@
+package rollabletable
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Matches markdown links like '[Link Label](path/to/table)' with a group for 'path/to/table' or
+// Internal links like '[[path/to/table]]' with a group for 'path/to/table'
+// Group 1: Either '[foo](' or '[['; Group 2: The path to the table; Group 3: Either ')' or '|foo]]' or ']]'
+var LinkPattern = regexp.MustCompile(`(\[[^\]]+\]\(|\[\[)(.+?)(\)|\|.+?\]\]|\]\])`)
+
+// Problem is something wrong with a table found by Validate
+type Problem struct {
+	Table   string
+	Message string
+}
+
+func (p Problem) String() string {
+	return p.Table + ": " + p.Message
+}
+
+// Validate checks a table for results of its dice that land on no row, rows that overlap, rows that can never
+// be rolled, entries listed more than once and links that can't be followed. linkExists reports whether a link
+// leads to a table, links aren't checked if it is nil.
+func Validate(table RollableTable, linkExists func(link string) bool) []Problem {
+	var problems []Problem
+	report := func(format string, a ...any) {
+		problems = append(problems, Problem{Table: table.Name, Message: fmt.Sprintf(format, a...)})
+	}
+
+	distribution := table.dice.Distribution()
+	var gaps []int
+	reached := make([]bool, len(table.rows))
+	for _, result := range distribution.Results() {
+		found := false
+		for i, row := range table.rows {
+			if row.contains(result) {
+				reached[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			gaps = append(gaps, result)
+		}
+	}
+	for _, gap := range groupResults(gaps) {
+		report("rolling %s on %s picks no row", gap.Range(), table.dice)
+	}
+
+	for i, row := range table.rows {
+		for _, earlier := range table.rows[:i] {
+			if row.Min <= earlier.Max && earlier.Min <= row.Max {
+				overlap := Row{Min: row.Min, Max: row.Max}
+				if earlier.Min > overlap.Min {
+					overlap.Min = earlier.Min
+				}
+				if earlier.Max < overlap.Max {
+					overlap.Max = earlier.Max
+				}
+				report("rows %s and %s overlap, %s always picks row %s", earlier.Range(), row.Range(), overlap.Range(), earlier.Range())
+			}
+		}
+		if !reached[i] {
+			report("row %s can never be rolled with %s", row.Range(), table.dice)
+		}
+	}
+
+	rowsByEntry := map[string][]string{}
+	var entries []string
+	for i, row := range table.rows {
+		entry := strings.TrimSpace(row.Entry)
+		if _, ok := rowsByEntry[entry]; !ok {
+			entries = append(entries, entry)
+		}
+		if row.group != 0 && i > 0 && table.rows[i-1].group == row.group {
+			continue // the same written row as the one before it
+		}
+		rowsByEntry[entry] = append(rowsByEntry[entry], row.Range())
+	}
+	for _, entry := range entries {
+		if len(rowsByEntry[entry]) > 1 {
+			report("entry '%s' is listed in rows %s", entry, strings.Join(rowsByEntry[entry], ", "))
+		}
+	}
+
+	if linkExists != nil {
+		for _, row := range table.rows {
+			for _, link := range LinkPattern.FindAllStringSubmatch(row.Entry, -1) {
+				if !linkExists(link[2]) {
+					report("row %s links to '%s' which isn't a table", row.Range(), link[2])
+				}
+			}
+		}
+	}
+	for _, row := range table.rows {
+		if row.Subtable != nil {
+			problems = append(problems, Validate(*row.Subtable, linkExists)...)
+		}
+	}
+	return problems
+}
+
+// groupResults collects results into runs of consecutive numbers
+func groupResults(results []int) []Row {
+	sort.Ints(results)
+	var groups []Row
+	for _, result := range results {
+		if len(groups) > 0 && groups[len(groups)-1].Max == result-1 {
+			groups[len(groups)-1].Max = result
+			continue
+		}
+		groups = append(groups, Row{Min: result, Max: result})
+	}
+	return groups
+}
@@ -0,0 +1,74 @@
+package rollabletable
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func messages(problems []Problem) []string {
+	var messages []string
+	for _, problem := range problems {
+		messages = append(messages, problem.Message)
+	}
+	return messages
+}
+
+func TestValidate_clean(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("| 2d6 | result |\n|---|---|\n| 2-6 | low |\n| 7 | [[Seven]] |\n| 8-12 | high |")), "clean")
+	assert.NoError(t, err)
+	assert.Empty(t, Validate(table, func(link string) bool { return link == "Seven" }))
+}
+
+func TestValidate_problems(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader(
+		"| 2d6 | result |\n|---|---|\n| 2-5 | low |\n| 4-6 | low |\n| 8-12 | [high](High) |\n| 13 | [[Never]] |")), "broken")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"rolling 7 on 2d6 picks no row",
+		"rows 2-5 and 4-6 overlap, 4-5 always picks row 2-5",
+		"row 13 can never be rolled with 2d6",
+		"entry 'low' is listed in rows 2-5, 4-6",
+		"row 8-12 links to 'High' which isn't a table",
+		"row 13 links to 'Never' which isn't a table",
+	}, messages(Validate(table, func(link string) bool { return false })))
+	assert.Len(t, Validate(table, nil), 4)
+}
+
+func TestValidate_shadowedRow(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("| d4 | result |\n|---|---|\n| 1-4 | all |\n| 2 | two |")), "shadowed")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"rows 1-4 and 2 overlap, 2 always picks row 1-4",
+		"row 2 can never be rolled with 1d4",
+	}, messages(Validate(table, nil)))
+}
+
+func TestValidate_subList(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("* Sword\n  * rusty\n  * rusty\n  * [[Runes]]\n* Shield\n")), "weapons")
+	assert.NoError(t, err)
+	problems := Validate(table, func(link string) bool { return false })
+	assert.Equal(t, []Problem{
+		{Table: "weapons#Sword", Message: "entry 'rusty' is listed in rows 1, 2"},
+		{Table: "weapons#Sword", Message: "row 3 links to 'Runes' which isn't a table"},
+	}, problems)
+}
+
+func TestValidate_rollList(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("| d6 | result |\n|---|---|\n| 1, 3 | rain |\n| 2 or 4 | sun |\n| 5-6 | rain |")), "weather")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"entry 'rain' is listed in rows 1, 5-6"}, messages(Validate(table, nil)))
+}
+
+func Test_groupResults(t *testing.T) {
+	assert.Equal(t, []Row{{Min: 1, Max: 3, Entry: ""}, {Min: 5, Max: 5, Entry: ""}, {Min: 7, Max: 8, Entry: ""}}, groupResults([]int{8, 1, 2, 3, 5, 7}))
+	assert.Empty(t, groupResults(nil))
+}
+
+func TestValidate_keepExploding(t *testing.T) {
+	table, err := ParseRollableTable(*bufio.NewScanner(strings.NewReader("| 1d6!kh1 | result |\n|---|---|\n| 1-6 | low |\n| 7+ | high |")), "exploding")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"row 7+ can never be rolled with 1d6!kh1"}, messages(Validate(table, nil)))
+}